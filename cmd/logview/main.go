@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+// logview reads wlog JsonFormat files and renders them as colored text.
+//
+// Example:
+//
+//	go run ./cmd/logview -level=warn -req_id=xxx ./ops.log
+//	go run ./cmd/logview -f -attr=request.route=/api/v1/users ./ops.log
+//	cat ./ops.log | go run ./cmd/logview -since=1h
+func main() {
+	var attrPairs []string
+
+	follow := flag.Bool("f", false, "Wait for new lines like `tail -f`, only the first file is followed")
+	level := flag.String("level", "", "Minimum level: debug, info, warn, error")
+	since := flag.String("since", "", "Show records after time, RFC3339 or duration ago (e.g. 30m)")
+	until := flag.String("until", "", "Show records before time, RFC3339 or duration ago (e.g. 10m)")
+	reqId := flag.String("req_id", "", "Filter by req_id")
+	traceId := flag.String("trace_id", "", "Filter by trace_id")
+	noColor := flag.Bool("no-color", false, "Disable color")
+	flag.Func("attr", "Filter by attribute equality key=value, group key is joined by dot, repeatable", func(s string) error {
+		attrPairs = append(attrPairs, s)
+		return nil
+	})
+	flag.Parse()
+
+	filter, err := newFilter(*level, *since, *until, *reqId, *traceId, attrPairs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	viewer := wlog.NewViewer(os.Stdout, *noColor, filter)
+
+	files := flag.Args()
+	if len(files) == 0 {
+		err = viewer.Read(os.Stdin)
+		exitIfError(err)
+		return
+	}
+
+	if *follow {
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		err = viewer.Tail(ctx, files[0], 0)
+		exitIfError(err)
+		return
+	}
+
+	for _, filename := range files {
+		file, err := os.Open(filename)
+		exitIfError(err)
+		err = viewer.Read(file)
+		file.Close()
+		exitIfError(err)
+	}
+}
+
+func newFilter(level, since, until, reqId, traceId string, attrPairs []string) (*wlog.RecordFilter, error) {
+	filter := &wlog.RecordFilter{
+		ReqId:   reqId,
+		TraceId: traceId,
+	}
+
+	if level != "" {
+		var lvl slog.Level
+		err := lvl.UnmarshalText([]byte(level))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level: %w", err)
+		}
+		filter.Level = &lvl
+	}
+
+	var err error
+	filter.Since, err = parseTime(since)
+	if err != nil {
+		return nil, fmt.Errorf("invalid since: %w", err)
+	}
+	filter.Until, err = parseTime(until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid until: %w", err)
+	}
+
+	filter.Attrs, err = wlog.ParseAttrFilters(attrPairs)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	ago, err := time.ParseDuration(value)
+	if err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func exitIfError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
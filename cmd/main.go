@@ -1,12 +1,12 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 
 	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/inject"
-	"github.com/KScaesar/go-layout/pkg/utility"
 	"github.com/KScaesar/go-layout/pkg/utility/wlog"
 )
 
@@ -31,6 +31,7 @@ func main() {
 	// Init is required before get default global variables
 	logWriter := pkg.Init(conf)
 	defer logWriter.Close()
+	pkg.WatchConfig(conf)
 
 	shutdown := pkg.Shutdown()
 	defer func() {
@@ -40,16 +41,5 @@ func main() {
 		<-shutdown.WaitChannel()
 	}()
 
-	infra, err := inject.NewInfra(conf)
-	if err != nil {
-		return
-	}
-	svc := inject.NewService(conf, infra)
-	mux := inject.NewFiberRouter(conf, infra.MySql, svc)
-	// mux := inject.NewGinRouter(conf, infra.MySql, svc)
-
-	// server start
-	utility.ServeO11YMetric(conf.O11Y.Port, shutdown, pkg.Logger().Slog())
-	inject.ServeFiber(conf.Http.Port, conf.Http.Debug, mux)
-	// inject.ServeGin(conf.Http.Port, mux)
+	err = inject.NewLifecycle(conf, shutdown).Start(context.Background())
 }
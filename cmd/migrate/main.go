@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/adapters/datastore"
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+// migrate applies the embedded migrations of datastore to pkg.Config.MySql.
+//
+// Example:
+//
+//	go run ./cmd/migrate -conf=./configs/config.yml up
+//	go run ./cmd/migrate -conf=./configs/config.yml down
+//	go run ./cmd/migrate -conf=./configs/config.yml status
+//	go run ./cmd/migrate -conf=./configs/config.yml to 1
+func main() {
+	wlogger := wlog.NewStderrLoggerWhenNormal(false)
+	pkg.Logger().PointToNew(wlogger)
+
+	err := run()
+	if err != nil {
+		wlogger.Slog().Error("migrate fail", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: migrate [flags] up|down|status|to {version}")
+		flag.PrintDefaults()
+	}
+	conf := pkg.MustLoadConfig()
+
+	db, err := sql.Open("mysql", conf.MySql.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := datastore.NewMySqlMigrator(db, pkg.Logger().Slog())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch flag.Arg(0) {
+	case "up":
+		return migrator.Up(ctx)
+
+	case "down":
+		return migrator.Down(ctx)
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		bData, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(bData))
+		return nil
+
+	case "to":
+		version, err := strconv.ParseUint(flag.Arg(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", flag.Arg(1), err)
+		}
+		return migrator.To(ctx, uint(version))
+	}
+
+	flag.Usage()
+	return fmt.Errorf("unknown command %q", flag.Arg(0))
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"fmt"
 	"log/slog"
 	"time"
 
@@ -9,7 +8,6 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/KScaesar/go-layout/pkg"
-	"github.com/KScaesar/go-layout/pkg/adapters"
 	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
@@ -33,21 +31,8 @@ func HelloGin(hack utility.Hack) func(c *gin.Context) {
 }
 
 func HelloFiber() fiber.Handler {
-	return func(c *fiber.Ctx) (err error) {
-		c.UserContext()
-
-		defer func() {
-			if err != nil {
-				err = adapters.HandleErrorByFiber(c, err)
-			}
-		}()
-
-		// err = nil
-		err = pkg.ErrInvalidUsername
-		if err != nil {
-			return fmt.Errorf("xxx_service: %w", err)
-		}
-
+	return func(c *fiber.Ctx) error {
+		// the returned error is converted by wfiber.Routes
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{"acs": "hello"})
 	}
 }
@@ -20,11 +20,13 @@ func TestMain(m *testing.M) {
 	// wlogger := wlog.NewStderrLoggerWhenDebug()
 	wlogger := wlog.NewDiscardLogger()
 	pkg.Logger().PointToNew(wlogger)
+	pkg.EventLogger().PointToNew(wlog.NewEventLogger(io.Discard))
 
 	// DownDocker := testdata.UpDocker(true, &testConfig)
 
 	goleak.VerifyTestMain(m,
 		goleak.IgnoreCurrent(),
+		goleak.IgnoreAnyFunction("github.com/valyala/fasthttp.updateServerDate.func1"), // fiber.App.Test
 		goleak.Cleanup(func(code int) {
 			pkg.Shutdown().Notify(nil)
 			<-pkg.Shutdown().WaitChannel()
@@ -1,19 +1,28 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
 
+	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/app"
+	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 func RegisterUser(svc app.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req app.RegisterUserRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err))
+			return
+		}
 
 		ctx := c.Request.Context()
-		err := svc.RegisterUser(ctx, &req)
+		err = svc.RegisterUser(ctx, &req)
 		if err != nil {
 			c.Error(err)
 			return
@@ -26,6 +35,11 @@ func RegisterUser(svc app.UserService) gin.HandlerFunc {
 func QueryMultiUser(svc app.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req app.QueryMultiUserRequest
+		err := c.ShouldBindQuery(&req)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err))
+			return
+		}
 
 		ctx := c.Request.Context()
 		resp, err := svc.QueryMultiUser(ctx, &req)
@@ -37,3 +51,195 @@ func QueryMultiUser(svc app.UserService) gin.HandlerFunc {
 		c.JSON(http.StatusOK, resp)
 	}
 }
+
+func LoginUser(svc app.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req app.LoginUserRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		resp, err := svc.LoginUser(ctx, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+func RefreshToken(svc app.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req app.RefreshTokenRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		resp, err := svc.RefreshToken(ctx, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// LogoutUser requires wgin.Authenticate
+func LogoutUser(svc app.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req app.LogoutUserRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err))
+			return
+		}
+		req.AccessToken, _ = utility.ParseBearerToken(c.GetHeader("Authorization"))
+
+		ctx := c.Request.Context()
+		err = svc.LogoutUser(ctx, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// UpdateUserPassword requires wgin.Authenticate, the user can only update self password.
+func UpdateUserPassword(svc app.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req app.UpdateUserPasswordRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		principal, _ := utility.CtxGetPrincipal(ctx)
+		req.UserId = principal.UserId
+
+		err = svc.UpdateUserPassword(ctx, &req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+//
+
+func RegisterUserFiber(svc app.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req app.RegisterUserRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err)
+		}
+
+		err = svc.RegisterUser(c.UserContext(), &req)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(nil)
+	}
+}
+
+func LoginUserFiber(svc app.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req app.LoginUserRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err)
+		}
+
+		resp, err := svc.LoginUser(c.UserContext(), &req)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
+
+func RefreshTokenFiber(svc app.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req app.RefreshTokenRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err)
+		}
+
+		resp, err := svc.RefreshToken(c.UserContext(), &req)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
+
+// LogoutUserFiber requires wfiber.Authenticate
+func LogoutUserFiber(svc app.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req app.LogoutUserRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err)
+		}
+		req.AccessToken, _ = utility.ParseBearerToken(c.Get(fiber.HeaderAuthorization))
+
+		err = svc.LogoutUser(c.UserContext(), &req)
+		if err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// QueryMultiUserFiber requires wfiber.Authenticate
+func QueryMultiUserFiber(svc app.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req app.QueryMultiUserRequest
+		err := c.QueryParser(&req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err)
+		}
+
+		resp, err := svc.QueryMultiUser(c.UserContext(), &req)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
+
+// UpdateUserPasswordFiber requires wfiber.Authenticate, the user can only update self password.
+func UpdateUserPasswordFiber(svc app.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req app.UpdateUserPasswordRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return fmt.Errorf("%w: %w", pkg.ErrInvalidParam, err)
+		}
+
+		ctx := c.UserContext()
+		principal, _ := utility.CtxGetPrincipal(ctx)
+		req.UserId = principal.UserId
+
+		err = svc.UpdateUserPassword(ctx, &req)
+		if err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
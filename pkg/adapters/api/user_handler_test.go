@@ -0,0 +1,173 @@
+package api_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/KScaesar/go-layout/pkg/adapters"
+	"github.com/KScaesar/go-layout/pkg/adapters/api"
+	"github.com/KScaesar/go-layout/pkg/app"
+	"github.com/KScaesar/go-layout/pkg/utility"
+	"github.com/KScaesar/go-layout/pkg/utility/wfiber"
+)
+
+// TestUpdateUserPasswordFiber_retryOnDeadlock
+// the use case is replayed in a new transaction when the database reports deadlock
+func TestUpdateUserPasswordFiber_retryOnDeadlock(t *testing.T) {
+	db, statements := newRecordGorm(t)
+
+	user, err := app.RegisterUser(&app.RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	userRepo := app.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().
+		LockUserById(gomock.Any(), user.Id).
+		Return(*user, nil).
+		Times(2)
+
+	errDeadlock := &mysqlDriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	attempts := 0
+	userRepo.EXPECT().
+		UpdateUser(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, user *app.User) error {
+			attempts++
+			assert.NotSame(t, db, utility.CtxGetGormTX(ctx, db))
+			if attempts == 1 {
+				return fmt.Errorf("update user: %w", errDeadlock)
+			}
+			return nil
+		}).
+		Times(2)
+
+	tokens := app.NewMockTokenService(ctrl)
+	tokens.EXPECT().
+		RevokeAllTokens(gomock.Any(), user.Id).
+		Return(nil).
+		Times(1)
+
+	transaction := utility.NewGormEasyTransaction(db, utility.WithTxRetry(3, time.Millisecond))
+	svc := app.NewUserUseCase(userRepo, tokens, transaction)
+
+	router := fiber.New(fiber.Config{ErrorHandler: adapters.HandleErrorByFiber})
+	authenticate := func(c *fiber.Ctx) error {
+		c.SetUserContext(utility.CtxWithPrincipal(c.UserContext(), utility.Principal{UserId: user.Id}))
+		return c.Next()
+	}
+	wfiber.NewRoutes(router, adapters.HandleErrorByFiber).
+		Group("/api/v1", authenticate).
+		Put("/users/me/password", api.UpdateUserPasswordFiber(svc))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/users/me/password", strings.NewReader(`{"old_password":"12345678","new_password":"87654321"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := router.Test(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode, testHttpResponseStringBody(t, resp))
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}, *statements)
+}
+
+func TestRegisterUserFiber(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	svc := app.NewMockUserService(ctrl)
+	svc.EXPECT().
+		RegisterUser(gomock.Any(), &app.RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"}).
+		Return(nil).
+		Times(1)
+
+	router := fiber.New(fiber.Config{ErrorHandler: adapters.HandleErrorByFiber})
+	wfiber.NewRoutes(router, adapters.HandleErrorByFiber).
+		Post("/api/v1/users", api.RegisterUserFiber(svc))
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			name: "registered",
+			body: `{"username":"Caesar","email":"caesar@example.com","password":"12345678"}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "malformed body",
+			body: `{"username":`,
+			code: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := router.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode, testHttpResponseStringBody(t, resp))
+		})
+	}
+}
+
+// newRecordGorm records the transaction statements without database
+func newRecordGorm(t *testing.T) (*gorm.DB, *[]string) {
+	statements := new([]string)
+	sqlDB := sql.OpenDB(&recordConnector{statements: statements})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+	return db, statements
+}
+
+type recordConnector struct {
+	statements *[]string
+}
+
+func (r *recordConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordConn{statements: r.statements}, nil
+}
+
+func (r *recordConnector) Driver() driver.Driver { return nil }
+
+type recordConn struct {
+	statements *[]string
+}
+
+func (r *recordConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *recordConn) Close() error { return nil }
+
+func (r *recordConn) Begin() (driver.Tx, error) {
+	*r.statements = append(*r.statements, "BEGIN")
+	return r, nil
+}
+
+func (r *recordConn) Commit() error {
+	*r.statements = append(*r.statements, "COMMIT")
+	return nil
+}
+
+func (r *recordConn) Rollback() error {
+	*r.statements = append(*r.statements, "ROLLBACK")
+	return nil
+}
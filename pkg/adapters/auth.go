@@ -0,0 +1,202 @@
+package adapters
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/app"
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+// exampleJwtPublicKey is the public key of keys/example.key, which is committed to the repository
+const exampleJwtPublicKey = "02e660525f131179c3d09cef5827dfde35dba15115d73f752f587e9841b8bd7e"
+
+// NewJwtKey refuses keys/example.key unless Http.Debug is true,
+// since anyone can sign the tokens by the committed key.
+func NewJwtKey(conf *pkg.Config) (ed25519.PrivateKey, error) {
+	if conf.Filepath.JwtKey == "" {
+		return nil, errors.New("load jwt key: Filepath.JwtKey is required")
+	}
+	key, err := utility.LoadEd25519PrivateKey(conf.Filepath.JwtKey)
+	if err != nil {
+		return nil, fmt.Errorf("load jwt key: %w", err)
+	}
+
+	publicKey := hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	if publicKey == exampleJwtPublicKey && !conf.Http.Debug {
+		return nil, fmt.Errorf("load jwt key: %v is the example key, it is only allowed when Http.Debug is true", conf.Filepath.JwtKey)
+	}
+	return key, nil
+}
+
+// NewJwtAuthenticator issues the access and refresh tokens,
+// the revoked tokens are recorded in redis until they are expired.
+func NewJwtAuthenticator(conf *pkg.Config, key ed25519.PrivateKey, client *redis.Client) *JwtAuthenticator {
+	return &JwtAuthenticator{
+		codec:      utility.NewJwtCodec(key, conf.Auth.Issuer),
+		client:     client,
+		keys:       utility.NewKeyBuilder(),
+		accessTTL:  conf.Auth.AccessTokenTTL,
+		refreshTTL: conf.Auth.RefreshTokenTTL,
+	}
+}
+
+type JwtAuthenticator struct {
+	codec      *utility.JwtCodec
+	client     *redis.Client
+	keys       utility.KeyBuilder
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func (a *JwtAuthenticator) IssueToken(ctx context.Context, principal utility.Principal) (app.TokenResponse, error) {
+	accessToken, _, err := a.codec.Encode(principal, utility.AccessToken, a.accessTTL)
+	if err != nil {
+		return app.TokenResponse{}, fmt.Errorf("%w: %w", pkg.ErrSystem, err)
+	}
+	refreshToken, _, err := a.codec.Encode(principal, utility.RefreshToken, a.refreshTTL)
+	if err != nil {
+		return app.TokenResponse{}, fmt.Errorf("%w: %w", pkg.ErrSystem, err)
+	}
+
+	return app.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(a.accessTTL.Seconds()),
+	}, nil
+}
+
+// RefreshToken revokes the old refresh token by SET NX,
+// so the same refresh token can't be used twice even if the requests are concurrent.
+func (a *JwtAuthenticator) RefreshToken(ctx context.Context, refreshToken string, check func(ctx context.Context, principal utility.Principal) error) (app.TokenResponse, error) {
+	claims, err := a.decode(refreshToken, utility.RefreshToken)
+	if err != nil {
+		return app.TokenResponse{}, err
+	}
+	err = a.checkRevoked(ctx, &claims)
+	if err != nil {
+		return app.TokenResponse{}, err
+	}
+	if check != nil {
+		err = check(ctx, claims.Principal())
+		if err != nil {
+			return app.TokenResponse{}, err
+		}
+	}
+
+	revoked, err := a.revoke(ctx, &claims)
+	if err != nil {
+		return app.TokenResponse{}, err
+	}
+	if !revoked {
+		return app.TokenResponse{}, fmt.Errorf("%w: refresh token has been revoked", pkg.ErrUnauthorized)
+	}
+
+	return a.IssueToken(ctx, claims.Principal())
+}
+
+func (a *JwtAuthenticator) RevokeToken(ctx context.Context, userId string, tokens ...string) error {
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		claims, err := a.decode(token, "")
+		if err != nil || claims.Subject != userId {
+			continue
+		}
+		_, err = a.revoke(ctx, &claims)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeAllTokens rejects the tokens of user which are issued before now,
+// the record is kept for the refresh token TTL, which is the longest lifetime of tokens.
+//
+// The precision is second, same as jwt iat,
+// so the tokens issued in the same second are still valid, e.g. login right after changing password.
+func (a *JwtAuthenticator) RevokeAllTokens(ctx context.Context, userId string) error {
+	err := a.client.Set(ctx, a.revokedBeforeKey(userId), time.Now().Unix(), a.refreshTTL).Err()
+	if err != nil {
+		return ConvertErrorFromRedis(err)
+	}
+	return nil
+}
+
+// VerifyAccessToken is utility.TokenVerifier, see wfiber.Authenticate and wgin.Authenticate
+func (a *JwtAuthenticator) VerifyAccessToken(ctx context.Context, accessToken string) (utility.Principal, error) {
+	claims, err := a.decode(accessToken, utility.AccessToken)
+	if err != nil {
+		return utility.Principal{}, err
+	}
+	err = a.checkRevoked(ctx, &claims)
+	if err != nil {
+		return utility.Principal{}, err
+	}
+	return claims.Principal(), nil
+}
+
+//
+
+func (a *JwtAuthenticator) decode(token string, kind utility.TokenKind) (utility.TokenClaims, error) {
+	claims, err := a.codec.Decode(token, kind)
+	if errors.Is(err, utility.ErrTokenExpired) {
+		return claims, fmt.Errorf("%w: %w", pkg.ErrTokenExpired, err)
+	}
+	if err != nil {
+		return claims, fmt.Errorf("%w: %w", pkg.ErrUnauthorized, err)
+	}
+	return claims, nil
+}
+
+// revoke keeps the jti until the token is expired, false indicates the token has been revoked.
+func (a *JwtAuthenticator) revoke(ctx context.Context, claims *utility.TokenClaims) (bool, error) {
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return true, nil
+	}
+
+	ok, err := a.client.SetNX(ctx, a.revokedKey(claims.ID), claims.Subject, ttl).Result()
+	if err != nil {
+		return false, ConvertErrorFromRedis(err)
+	}
+	return ok, nil
+}
+
+// checkRevoked reads the jti of RevokeToken and the time of RevokeAllTokens by one round trip
+func (a *JwtAuthenticator) checkRevoked(ctx context.Context, claims *utility.TokenClaims) error {
+	values, err := a.client.MGet(ctx, a.revokedKey(claims.ID), a.revokedBeforeKey(claims.Subject)).Result()
+	if err != nil {
+		return ConvertErrorFromRedis(err)
+	}
+
+	if values[0] != nil {
+		return fmt.Errorf("%w: %v token has been revoked", pkg.ErrUnauthorized, claims.Kind)
+	}
+	if values[1] != nil {
+		revokedBefore, err := strconv.ParseInt(values[1].(string), 10, 64)
+		if err == nil && claims.IssuedAt != nil && claims.IssuedAt.Unix() < revokedBefore {
+			return fmt.Errorf("%w: %v token has been revoked", pkg.ErrUnauthorized, claims.Kind)
+		}
+	}
+	return nil
+}
+
+func (a *JwtAuthenticator) revokedKey(jti string) string {
+	return a.keys.Init().BuildString("auth:revoked:", jti)
+}
+
+func (a *JwtAuthenticator) revokedBeforeKey(userId string) string {
+	return a.keys.Init().BuildString("auth:revoked_before:", userId)
+}
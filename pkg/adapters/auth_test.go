@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+func newTestJwtAuthenticator(t *testing.T) (*JwtAuthenticator, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	conf := &pkg.Config{Auth: pkg.Auth{
+		Issuer:          "auth_test",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}}
+	return NewJwtAuthenticator(conf, key, client), server
+}
+
+func TestJwtAuthenticator_RefreshToken(t *testing.T) {
+	auth, server := newTestJwtAuthenticator(t)
+	ctx := context.Background()
+	principal := utility.Principal{UserId: "u1", Username: "Caesar"}
+
+	token, err := auth.IssueToken(ctx, principal)
+	require.NoError(t, err)
+
+	// the refresh token is not consumed when check fails
+	_, err = auth.RefreshToken(ctx, token.RefreshToken, func(ctx context.Context, got utility.Principal) error {
+		assert.Equal(t, principal, got)
+		return pkg.ErrUserDisabled
+	})
+	assert.ErrorIs(t, err, pkg.ErrUserDisabled)
+
+	rotated, err := auth.RefreshToken(ctx, token.RefreshToken, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, token.RefreshToken, rotated.RefreshToken)
+
+	got, err := auth.VerifyAccessToken(ctx, rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, principal, got)
+
+	// reuse of the rotated refresh token is rejected
+	_, err = auth.RefreshToken(ctx, token.RefreshToken, nil)
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+
+	// the access token can't be used as refresh token
+	_, err = auth.RefreshToken(ctx, rotated.AccessToken, nil)
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+
+	// the revocation is kept until the token is expired
+	keys := server.Keys()
+	require.Len(t, keys, 1)
+	assert.InDelta(t, time.Hour, server.TTL(keys[0]), float64(time.Minute))
+}
+
+func TestJwtAuthenticator_RevokeToken(t *testing.T) {
+	auth, _ := newTestJwtAuthenticator(t)
+	ctx := context.Background()
+
+	token, err := auth.IssueToken(ctx, utility.Principal{UserId: "u1"})
+	require.NoError(t, err)
+
+	// the tokens of other user and the invalid tokens are ignored
+	require.NoError(t, auth.RevokeToken(ctx, "u2", token.AccessToken, token.RefreshToken))
+	require.NoError(t, auth.RevokeToken(ctx, "u1", "invalid-token", ""))
+	_, err = auth.VerifyAccessToken(ctx, token.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, auth.RevokeToken(ctx, "u1", token.AccessToken, token.RefreshToken))
+	_, err = auth.VerifyAccessToken(ctx, token.AccessToken)
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+	_, err = auth.RefreshToken(ctx, token.RefreshToken, nil)
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+
+	_, err = auth.VerifyAccessToken(ctx, "invalid-token")
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+}
+
+func TestJwtAuthenticator_RevokeAllTokens(t *testing.T) {
+	auth, _ := newTestJwtAuthenticator(t)
+	ctx := context.Background()
+
+	other, err := auth.IssueToken(ctx, utility.Principal{UserId: "u2"})
+	require.NoError(t, err)
+	old, err := auth.IssueToken(ctx, utility.Principal{UserId: "u1"})
+	require.NoError(t, err)
+
+	// iat is in seconds, the tokens issued in the same second are still valid
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	require.NoError(t, auth.RevokeAllTokens(ctx, "u1"))
+
+	_, err = auth.VerifyAccessToken(ctx, old.AccessToken)
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+	_, err = auth.RefreshToken(ctx, old.RefreshToken, nil)
+	assert.ErrorIs(t, err, pkg.ErrUnauthorized)
+
+	_, err = auth.VerifyAccessToken(ctx, other.AccessToken)
+	assert.NoError(t, err)
+
+	// login again after changing password
+	token, err := auth.IssueToken(ctx, utility.Principal{UserId: "u1"})
+	require.NoError(t, err)
+	_, err = auth.VerifyAccessToken(ctx, token.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestNewJwtKey(t *testing.T) {
+	conf := &pkg.Config{}
+
+	_, err := NewJwtKey(conf)
+	assert.ErrorContains(t, err, "Filepath.JwtKey is required")
+
+	conf.Filepath.JwtKey = "../../keys/example.key"
+	_, err = NewJwtKey(conf)
+	assert.ErrorContains(t, err, "example key")
+
+	conf.Http.Debug = true
+	_, err = NewJwtKey(conf)
+	assert.NoError(t, err)
+}
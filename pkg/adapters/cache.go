@@ -3,6 +3,7 @@ package adapters
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/allegro/bigcache/v3"
 
 	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 func NewLocalCache() (*bigcache.BigCache, error) {
@@ -22,7 +24,23 @@ func NewLocalCache() (*bigcache.BigCache, error) {
 	}
 
 	id := fmt.Sprintf("LocalCache(%p)", cache)
-	pkg.Shutdown().AddPriorityShutdownAction(2, id, cache.Close)
+	err = pkg.HealthRegistry().Register(utility.HealthChecker{
+		Name: id,
+		Check: func(ctx context.Context) error {
+			// bigcache is in-process, only capacity is reported as non-critical
+			if config.HardMaxCacheSize > 0 && cache.Capacity() >= config.HardMaxCacheSize*1024*1024 {
+				return errors.New("bigcache is full")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		cache.Close()
+		return nil, fmt.Errorf("register bigcache health: %w", err)
+	}
+	pkg.Shutdown().AddPriorityShutdownActionCtx(2, id, func(ctx context.Context) error {
+		return utility.StopWithCtx(ctx, cache.Close)
+	})
 	return cache, nil
 }
 
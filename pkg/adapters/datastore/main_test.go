@@ -0,0 +1,52 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+var testConfig = pkg.Config{
+	MySql: pkg.MySql{
+		User:     "root",
+		Password: "1234",
+		Database: "testdata",
+	},
+}
+
+func TestMain(m *testing.M) {
+	wlogger := wlog.NewDiscardLogger()
+	pkg.Logger().PointToNew(wlogger)
+	pkg.EventLogger().PointToNew(wlog.NewEventLogger(io.Discard))
+
+	DownDocker := utility.UpDocker(true, []utility.DockerService{
+		utility.NewMySqlService("mysql", &testConfig.MySql, migrateTestMySql),
+		utility.NewRedisService("redis", &testConfig.Redis, nil),
+	})
+
+	code := m.Run()
+	DownDocker()
+	os.Exit(code)
+}
+
+func migrateTestMySql(ctx context.Context) error {
+	db, err := sql.Open("mysql", testConfig.MySql.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := NewMySqlMigrator(db, pkg.Logger().Slog())
+	if err != nil {
+		return err
+	}
+	return migrator.Up(ctx)
+}
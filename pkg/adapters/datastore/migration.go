@@ -0,0 +1,29 @@
+package datastore
+
+import (
+	"database/sql"
+	"embed"
+	"log/slog"
+
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+// 新增 migration 時, 版本號遞增, 已經套用的 sql 檔案不可修改, 否則 checksum 驗證會失敗
+//
+//	migrations/{version}_{name}.up.sql
+//	migrations/{version}_{name}.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+func Migrations() ([]utility.Migration, error) {
+	return utility.LoadMigrations(migrationFS, "migrations")
+}
+
+func NewMySqlMigrator(db *sql.DB, logger *slog.Logger) (*utility.Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return utility.NewMigrator(db, migrations, logger), nil
+}
@@ -0,0 +1,19 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, uint(i+1), migration.Version, "migration version must be continuous")
+		assert.NotEmpty(t, migration.Down, "migration %v_%v: down.sql is required", migration.Version, migration.Name)
+	}
+}
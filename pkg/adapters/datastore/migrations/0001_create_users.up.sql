@@ -0,0 +1,17 @@
+CREATE TABLE users
+(
+    id         CHAR(26)        NOT NULL,
+    username   VARCHAR(64)     NOT NULL,
+    email      VARCHAR(255)    NOT NULL,
+    password   VARCHAR(255)    NOT NULL,
+    status     VARCHAR(16)     NOT NULL,
+    version    BIGINT UNSIGNED NOT NULL DEFAULT 1,
+    created_at DATETIME(3)     NOT NULL,
+    updated_at DATETIME(3)     NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_users_username (username),
+    UNIQUE KEY uk_users_email (email),
+    KEY idx_users_created_at (created_at, id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_unicode_ci;
@@ -2,10 +2,19 @@ package datastore
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/adapters"
 	"github.com/KScaesar/go-layout/pkg/app"
+	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 func NewUserMySQL(db *gorm.DB) *UserMySQL {
@@ -16,37 +25,199 @@ type UserMySQL struct {
 	db *gorm.DB
 }
 
+// LockUserById 使用 SELECT ... FOR UPDATE, 必須在 transaction 之中呼叫, 否則 lock 會立即釋放
 func (repo *UserMySQL) LockUserById(ctx context.Context, userId string) (app.User, error) {
-	// TODO implement me
-	panic("implement me")
+	var user app.User
+	err := utility.CtxGetGormTX(ctx, repo.db).WithContext(ctx).
+		Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("id = ?", userId).
+		Take(&user).Error
+	if err != nil {
+		return app.User{}, adapters.ConvertErrorFromMySQL(err)
+	}
+	return user, nil
 }
 
 func (repo *UserMySQL) CreteUser(ctx context.Context, user *app.User) error {
-	// TODO implement me
-	panic("implement me")
+	err := utility.CtxGetGormTX(ctx, repo.db).WithContext(ctx).
+		Create(user).Error
+	if err != nil {
+		return adapters.ConvertErrorFromMySQL(err)
+	}
+	return nil
 }
 
+// UpdateUser only writes the columns of User.ByUpdated,
+// it returns pkg.ErrVersionConflict if the user has been modified since it was read.
 func (repo *UserMySQL) UpdateUser(ctx context.Context, user *app.User) error {
-	// TODO implement me
-	panic("implement me")
+	if len(user.ByUpdated) == 0 {
+		return nil
+	}
+
+	columns := maps.Clone(user.ByUpdated.StdMap())
+	columns["version"] = gorm.Expr("version + 1")
+
+	tx := utility.CtxGetGormTX(ctx, repo.db).WithContext(ctx).
+		Model(&app.User{}).
+		Where("id = ? AND version = ?", user.Id, user.Version).
+		Updates(columns)
+	if tx.Error != nil {
+		return adapters.ConvertErrorFromMySQL(tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return pkg.ErrVersionConflict
+	}
+
+	user.Version++
+	user.ByUpdated = nil
+	return nil
 }
 
+// DeleteUser is a soft delete, app.User.Delete has marked the status as deleted.
 func (repo *UserMySQL) DeleteUser(ctx context.Context, user *app.User) error {
-	// TODO implement me
-	panic("implement me")
+	return repo.UpdateUser(ctx, user)
 }
 
 func (repo *UserMySQL) QueryUserById(ctx context.Context, userId string) (app.UserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+	var user app.User
+	err := utility.CtxGetGormTX(ctx, repo.db).WithContext(ctx).
+		Where("id = ? AND status <> ?", userId, app.UserStatusDeleted).
+		Take(&user).Error
+	if err != nil {
+		return app.UserResponse{}, adapters.ConvertErrorFromMySQL(err)
+	}
+	return app.ConvertUserResponse(&user), nil
 }
 
+// LoginUser does not distinguish the unknown username from the wrong password,
+// neither by the error nor by the response time.
 func (repo *UserMySQL) LoginUser(ctx context.Context, req *app.LoginUserRequest) (app.UserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+	var user app.User
+	err := utility.CtxGetGormTX(ctx, repo.db).WithContext(ctx).
+		Where("username = ? AND status <> ?", req.Username, app.UserStatusDeleted).
+		Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return app.UserResponse{}, app.VerifyUnknownUserPassword(req.Password)
+	}
+	if err != nil {
+		return app.UserResponse{}, adapters.ConvertErrorFromMySQL(err)
+	}
+
+	err = user.VerifyPassword(req.Password)
+	if err != nil {
+		return app.UserResponse{}, err
+	}
+	return app.ConvertUserResponse(&user), nil
 }
 
+// QueryMultiUserByFilter uses keyset pagination on (sort column, id),
+// the filter must be validated by app.QueryMultiUserRequest.Validate.
 func (repo *UserMySQL) QueryMultiUserByFilter(ctx context.Context, filter *app.QueryMultiUserRequest) (app.MultiUserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+	tx := utility.CtxGetGormTX(ctx, repo.db).WithContext(ctx).Model(&app.User{})
+
+	if filter.Status != "" {
+		tx = tx.Where("status = ?", filter.Status)
+	} else {
+		tx = tx.Where("status <> ?", app.UserStatusDeleted)
+	}
+	if filter.Username != "" {
+		tx = tx.Where("username LIKE ?", escapeLike(filter.Username)+"%")
+	}
+	if filter.Email != "" {
+		tx = tx.Where("email = ?", filter.Email)
+	}
+
+	column := string(filter.SortBy)
+	operator := ">"
+	if filter.Desc {
+		operator = "<"
+	}
+	if filter.Cursor != "" {
+		value, id, err := decodeUserCursor(filter.Cursor, filter.SortBy)
+		if err != nil {
+			return app.MultiUserResponse{}, err
+		}
+		tx = tx.Where(
+			"("+column+" "+operator+" ? OR ("+column+" = ? AND id "+operator+" ?))",
+			value, value, id,
+		)
+	}
+
+	var users []app.User
+	err := tx.
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Name: column}, Desc: filter.Desc},
+			{Column: clause.Column{Name: "id"}, Desc: filter.Desc},
+		}}).
+		Limit(filter.Limit + 1).
+		Find(&users).Error
+	if err != nil {
+		return app.MultiUserResponse{}, adapters.ConvertErrorFromMySQL(err)
+	}
+
+	resp := app.MultiUserResponse{Users: make([]app.UserResponse, 0, len(users))}
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+		resp.NextCursor = encodeUserCursor(&users[len(users)-1], filter.SortBy)
+	}
+	for i := range users {
+		resp.Users = append(resp.Users, app.ConvertUserResponse(&users[i]))
+	}
+	return resp, nil
+}
+
+//
+
+// userCursor is the last row of the previous page
+type userCursor struct {
+	Value string `json:"v"` // the value of sort column
+	Id    string `json:"id"`
+}
+
+func encodeUserCursor(user *app.User, sortBy app.UserSortBy) string {
+	cursor := userCursor{Id: user.Id}
+	switch sortBy {
+	case app.UserSortByUsername:
+		cursor.Value = user.Username
+	default:
+		cursor.Value = user.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	bData, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(bData)
+}
+
+func decodeUserCursor(text string, sortBy app.UserSortBy) (value any, id string, err error) {
+	var cursor userCursor
+	bData, err := base64.RawURLEncoding.DecodeString(text)
+	if err == nil {
+		err = json.Unmarshal(bData, &cursor)
+	}
+	if err == nil {
+		switch sortBy {
+		case app.UserSortByUsername:
+			value = cursor.Value
+		default:
+			value, err = time.Parse(time.RFC3339Nano, cursor.Value)
+		}
+	}
+	if err != nil {
+		return nil, "", utility.ErrorWithDetails(pkg.ErrInvalidParam, utility.ErrorDetail{
+			Field:   "cursor",
+			Message: "cursor is invalid",
+		})
+	}
+	return value, cursor.Id, nil
+}
+
+func escapeLike(text string) string {
+	var escaped []rune
+	for _, char := range text {
+		switch char {
+		case '\\', '%', '_':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, char)
+	}
+	return string(escaped)
 }
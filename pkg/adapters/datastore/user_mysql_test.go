@@ -0,0 +1,165 @@
+//go:build intg
+
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/app"
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+func newTestUserMySQL(t *testing.T) (*UserMySQL, *gorm.DB) {
+	db, err := gorm.Open(mysql.Open(testConfig.MySql.DSN()), &gorm.Config{})
+	require.NoError(t, err)
+	return NewUserMySQL(db), db
+}
+
+// newTestUser uses the prefix to isolate the rows of each test
+func newTestUser(t *testing.T, repo *UserMySQL, username string) *app.User {
+	user, err := app.RegisterUser(&app.RegisterUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "12345678",
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreteUser(context.Background(), user))
+	return user
+}
+
+func TestUserMySQL_CreteUser(t *testing.T) {
+	repo, _ := newTestUserMySQL(t)
+	ctx := context.Background()
+
+	user := newTestUser(t, repo, "CreateCaesar")
+
+	duplicate, err := app.RegisterUser(&app.RegisterUserRequest{
+		Username: user.Username,
+		Email:    "other@example.com",
+		Password: "12345678",
+	})
+	require.NoError(t, err)
+	err = repo.CreteUser(ctx, duplicate)
+	assert.ErrorIs(t, err, pkg.ErrExists)
+
+	resp, err := repo.QueryUserById(ctx, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, user.Username, resp.Username)
+
+	_, err = repo.QueryUserById(ctx, "not-exist")
+	assert.ErrorIs(t, err, pkg.ErrNotExists)
+}
+
+func TestUserMySQL_UpdateUser(t *testing.T) {
+	repo, db := newTestUserMySQL(t)
+	ctx := context.Background()
+
+	user := newTestUser(t, repo, "UpdateCaesar")
+
+	transaction := utility.NewGormEasyTransaction(db)
+	err := transaction(ctx, func(txCtx context.Context) error {
+		locked, err := repo.LockUserById(txCtx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		email := "update@example.com"
+		err = locked.UpdateInfo(&app.UpdateUserInfoRequest{Email: &email})
+		if err != nil {
+			return err
+		}
+		return repo.UpdateUser(txCtx, &locked)
+	})
+	require.NoError(t, err)
+
+	resp, err := repo.QueryUserById(ctx, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "update@example.com", resp.Email)
+
+	// user is the stale copy which has the version before the transaction
+	username := "StaleCaesar"
+	require.NoError(t, user.UpdateInfo(&app.UpdateUserInfoRequest{Username: &username}))
+	err = repo.UpdateUser(ctx, user)
+	assert.ErrorIs(t, err, pkg.ErrVersionConflict)
+
+	locked, err := repo.LockUserById(ctx, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), locked.Version)
+	require.NoError(t, locked.Delete())
+	require.NoError(t, repo.DeleteUser(ctx, &locked))
+
+	_, err = repo.QueryUserById(ctx, user.Id)
+	assert.ErrorIs(t, err, pkg.ErrNotExists)
+}
+
+func TestUserMySQL_LoginUser(t *testing.T) {
+	repo, db := newTestUserMySQL(t)
+	ctx := context.Background()
+
+	_, err := repo.LoginUser(ctx, &app.LoginUserRequest{Username: "UnknownCaesar", Password: "12345678"})
+	assert.ErrorIs(t, err, pkg.ErrIncorrectPassword)
+
+	// the uncommitted user is visible by the queries of the same tx
+	errRollback := errors.New("rollback")
+	err = utility.NewGormEasyTransaction(db)(ctx, func(txCtx context.Context) error {
+		user, err := app.RegisterUser(&app.RegisterUserRequest{Username: "LoginCaesar", Email: "login@example.com", Password: "12345678"})
+		require.NoError(t, err)
+		require.NoError(t, repo.CreteUser(txCtx, user))
+
+		resp, err := repo.LoginUser(txCtx, &app.LoginUserRequest{Username: user.Username, Password: "12345678"})
+		require.NoError(t, err)
+		assert.Equal(t, user.Id, resp.Id)
+
+		_, err = repo.QueryUserById(txCtx, user.Id)
+		require.NoError(t, err)
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+}
+
+func TestUserMySQL_QueryMultiUserByFilter(t *testing.T) {
+	repo, _ := newTestUserMySQL(t)
+	ctx := context.Background()
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		newTestUser(t, repo, fmt.Sprintf("PageCaesar%v", i))
+	}
+
+	filter := &app.QueryMultiUserRequest{
+		Username: "PageCaesar",
+		SortBy:   app.UserSortByUsername,
+		Desc:     true,
+		Limit:    2,
+	}
+	require.NoError(t, filter.Validate())
+
+	var usernames []string
+	for page := 0; ; page++ {
+		require.Less(t, page, total, "the pagination does not end")
+
+		resp, err := repo.QueryMultiUserByFilter(ctx, filter)
+		require.NoError(t, err)
+		for _, user := range resp.Users {
+			usernames = append(usernames, user.Username)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		filter.Cursor = resp.NextCursor
+	}
+	assert.Equal(t, []string{"PageCaesar4", "PageCaesar3", "PageCaesar2", "PageCaesar1", "PageCaesar0"}, usernames)
+
+	filter.Cursor = "invalid"
+	_, err := repo.QueryMultiUserByFilter(ctx, filter)
+	assert.ErrorIs(t, err, pkg.ErrInvalidParam)
+}
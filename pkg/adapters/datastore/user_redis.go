@@ -2,41 +2,136 @@ package datastore
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/adapters"
 	"github.com/KScaesar/go-layout/pkg/app"
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+const (
+	// userCacheVersion 修改 app.UserResponse 的欄位時必須更新, 舊版本的 key 會因為 TTL 自然消失
+	userCacheVersion = "v1"
+
+	userCacheTTL      = 10 * time.Minute
+	multiUserCacheTTL = time.Minute
+
+	// cacheJitterRatio 避免同時寫入的 key 同時過期, 造成 Cache Avalanche
+	cacheJitterRatio = 0.1
 )
 
 func NewUserRedis(client *redis.Client) *UserRedis {
-	return &UserRedis{client: client}
+	return &UserRedis{
+		client: client,
+		keys:   utility.NewKeyBuilder(),
+	}
 }
 
+// UserRedis
+//
+//   - user: {version}:user:{userId}
+//   - multi user: {version}:users:{filter hash}
+//   - tag: {version}:user:{userId}:lists, a set of multi user keys which contain the user
 type UserRedis struct {
 	client *redis.Client
+	keys   utility.KeyBuilder
+}
+
+func (repo *UserRedis) UserKey(userId string) string {
+	return repo.keys.InitWithVersion(userCacheVersion).BuildString(":user:", userId)
+}
+
+// MultiUserKey is deterministic, the same filter always gets the same key.
+func (repo *UserRedis) MultiUserKey(filter *app.QueryMultiUserRequest) string {
+	bData, _ := json.Marshal(filter)
+	sum := sha256.Sum256(bData)
+	return repo.keys.InitWithVersion(userCacheVersion).BuildString(":users:", hex.EncodeToString(sum[:16]))
+}
+
+func (repo *UserRedis) tagKey(userId string) string {
+	return repo.keys.InitWithVersion(userCacheVersion).BuildString(":user:", userId, ":lists")
 }
 
 func (repo *UserRedis) SetUser(ctx context.Context, key string, resp *app.UserResponse) error {
-	// TODO implement me
-	panic("implement me")
+	bData, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("key=%q: json.Marshal: %w: %w", key, pkg.ErrSystem, err)
+	}
+
+	err = repo.client.Set(ctx, key, bData, ttlWithJitter(userCacheTTL)).Err()
+	if err != nil {
+		return adapters.ConvertErrorFromRedis(err)
+	}
+	return nil
 }
 
+// SetMultiUser tags the key by each user, so DeleteUser can drop the lists which contain the user.
+//
+// The lists which should contain a new or changed user are not dropped,
+// they are refreshed by the shorter TTL.
 func (repo *UserRedis) SetMultiUser(ctx context.Context, key string, resp *app.MultiUserResponse) error {
-	// TODO implement me
-	panic("implement me")
+	bData, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("key=%q: json.Marshal: %w: %w", key, pkg.ErrSystem, err)
+	}
+
+	ttl := ttlWithJitter(multiUserCacheTTL)
+	_, err = repo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, bData, ttl)
+		for _, user := range resp.Users {
+			tag := repo.tagKey(user.Id)
+			pipe.SAdd(ctx, tag, key)
+			// the tag must live longer than the lists
+			pipe.Expire(ctx, tag, multiUserCacheTTL*2)
+		}
+		return nil
+	})
+	if err != nil {
+		return adapters.ConvertErrorFromRedis(err)
+	}
+	return nil
 }
 
-func (repo *UserRedis) DeleteUser(ctx context.Context, resp *app.User) error {
-	// TODO implement me
-	panic("implement me")
+// DeleteUser drops the user and every cached list which contains the user.
+func (repo *UserRedis) DeleteUser(ctx context.Context, user *app.User) error {
+	tag := repo.tagKey(user.Id)
+	lists, err := repo.client.SMembers(ctx, tag).Result()
+	if err != nil {
+		return adapters.ConvertErrorFromRedis(err)
+	}
+
+	keys := append([]string{repo.UserKey(user.Id), tag}, lists...)
+	err = repo.client.Del(ctx, keys...).Err()
+	if err != nil {
+		return adapters.ConvertErrorFromRedis(err)
+	}
+	return nil
 }
 
 func (repo *UserRedis) QueryUser(ctx context.Context, key string) (app.UserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+	logger := pkg.Logger().CtxGetLogger(ctx)
+	return adapters.GetRedisStringByType[app.UserResponse](repo.client, json.Unmarshal, logger, ctx, key)
 }
 
 func (repo *UserRedis) QueryMultiUser(ctx context.Context, key string) (app.MultiUserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+	logger := pkg.Logger().CtxGetLogger(ctx)
+	return adapters.GetRedisStringByType[app.MultiUserResponse](repo.client, json.Unmarshal, logger, ctx, key)
+}
+
+//
+
+func ttlWithJitter(ttl time.Duration) time.Duration {
+	jitter := time.Duration(float64(ttl) * cacheJitterRatio)
+	if jitter <= 0 {
+		return ttl
+	}
+	return ttl + rand.N(jitter)
 }
@@ -0,0 +1,62 @@
+//go:build intg
+
+package datastore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/app"
+)
+
+func newTestUserRedis(t *testing.T) *UserRedis {
+	client := redis.NewClient(&redis.Options{Addr: testConfig.Redis.Address()})
+	t.Cleanup(func() { client.Close() })
+	return NewUserRedis(client)
+}
+
+func TestUserRedis_MultiUserKey(t *testing.T) {
+	repo := newTestUserRedis(t)
+
+	key1 := repo.MultiUserKey(&app.QueryMultiUserRequest{Username: "Caesar", Limit: 10})
+	key2 := repo.MultiUserKey(&app.QueryMultiUserRequest{Username: "Caesar", Limit: 10})
+	key3 := repo.MultiUserKey(&app.QueryMultiUserRequest{Username: "Caesar", Limit: 20})
+	assert.Equal(t, key1, key2)
+	assert.NotEqual(t, key1, key3)
+}
+
+func TestUserRedis_DeleteUser(t *testing.T) {
+	repo := newTestUserRedis(t)
+	ctx := context.Background()
+
+	caesar := app.UserResponse{Id: "caesar", Username: "Caesar"}
+	brutus := app.UserResponse{Id: "brutus", Username: "Brutus"}
+
+	userKey := repo.UserKey(caesar.Id)
+	require.NoError(t, repo.SetUser(ctx, userKey, &caesar))
+
+	withCaesar := repo.MultiUserKey(&app.QueryMultiUserRequest{Username: "C"})
+	require.NoError(t, repo.SetMultiUser(ctx, withCaesar, &app.MultiUserResponse{Users: []app.UserResponse{caesar, brutus}}))
+	withoutCaesar := repo.MultiUserKey(&app.QueryMultiUserRequest{Username: "B"})
+	require.NoError(t, repo.SetMultiUser(ctx, withoutCaesar, &app.MultiUserResponse{Users: []app.UserResponse{brutus}}))
+
+	resp, err := repo.QueryUser(ctx, userKey)
+	require.NoError(t, err)
+	assert.Equal(t, caesar, resp)
+
+	require.NoError(t, repo.DeleteUser(ctx, &app.User{Id: caesar.Id}))
+
+	_, err = repo.QueryUser(ctx, userKey)
+	assert.ErrorIs(t, err, pkg.ErrNotExists)
+	_, err = repo.QueryMultiUser(ctx, withCaesar)
+	assert.ErrorIs(t, err, pkg.ErrNotExists)
+
+	multi, err := repo.QueryMultiUser(ctx, withoutCaesar)
+	require.NoError(t, err)
+	assert.Equal(t, []app.UserResponse{brutus}, multi.Users)
+}
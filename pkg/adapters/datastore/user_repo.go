@@ -2,7 +2,9 @@ package datastore
 
 import (
 	"context"
+	"log/slog"
 
+	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/app"
 	"github.com/KScaesar/go-layout/pkg/utility"
 )
@@ -26,7 +28,8 @@ func (repo *UserRepository) UpdateUser(ctx context.Context, user *app.User) erro
 	if err != nil {
 		return err
 	}
-	return repo.cache.DeleteUser(ctx, user)
+	repo.invalidateCache(ctx, user)
+	return nil
 }
 
 func (repo *UserRepository) DeleteUser(ctx context.Context, user *app.User) error {
@@ -34,7 +37,19 @@ func (repo *UserRepository) DeleteUser(ctx context.Context, user *app.User) erro
 	if err != nil {
 		return err
 	}
-	return repo.cache.DeleteUser(ctx, user)
+	repo.invalidateCache(ctx, user)
+	return nil
+}
+
+// invalidateCache 在 tx commit 之後才刪除 cache,
+// 避免 commit 之前, 其他請求把舊資料重新寫入 cache
+func (repo *UserRepository) invalidateCache(ctx context.Context, user *app.User) {
+	utility.AfterCommit(ctx, func(ctx context.Context) {
+		err := repo.cache.DeleteUser(ctx, user)
+		if err != nil {
+			pkg.Logger().CtxGetLogger(ctx).Error("invalidate user cache", slog.String("user_id", user.Id), slog.Any("err", err))
+		}
+	})
 }
 
 func (repo *UserRepository) QueryUserById(ctx context.Context, userId string) (app.UserResponse, error) {
@@ -50,7 +65,7 @@ func (repo *UserRepository) QueryUserById(ctx context.Context, userId string) (a
 		},
 		Guard: &repo.singleFlight,
 	}
-	return proxy.SafeReadPrimaryAndReplicaNode("userId:" + userId)
+	return proxy.SafeReadPrimaryAndReplicaNode(repo.cache.UserKey(userId))
 }
 
 func (repo *UserRepository) QueryMultiUserByFilter(ctx context.Context, filter *app.QueryMultiUserRequest) (app.MultiUserResponse, error) {
@@ -66,5 +81,5 @@ func (repo *UserRepository) QueryMultiUserByFilter(ctx context.Context, filter *
 		},
 		Guard: &repo.singleFlight,
 	}
-	return proxy.SafeReadPrimaryNode("xxxKey")
+	return proxy.SafeReadPrimaryNode(repo.cache.MultiUserKey(filter))
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/KScaesar/go-layout/pkg/utility"
 	"github.com/KScaesar/go-layout/pkg/utility/dataflow"
 	"github.com/KScaesar/go-layout/pkg/utility/wfiber"
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
 )
 
 var FiberO11YMetric = wfiber.NewO11YMetric(pkg.Version().ServiceName)
@@ -50,17 +51,15 @@ func (key *fiberMetadataKey) SetErrorCode(c *fiber.Ctx, errCode int) {
 
 //
 
-func NewErrorResponse(code int, message string) *ErrorResponse {
+func NewErrorResponse(code int, message string, details ...utility.ErrorDetail) *ErrorResponse {
 	return &ErrorResponse{
 		Code:    code,
 		Message: message,
+		Details: details,
 	}
 }
 
-type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
+type ErrorResponse = utility.ErrorResponse
 
 //
 
@@ -73,8 +72,10 @@ func HandleErrorByFiber(c *fiber.Ctx, err error) error {
 		logger.Warn("capture unknown error", slog.Any("err", Err))
 	}
 
+	// the internal error chain is logged, client only receives the public message
 	FiberMetadata.SetErrorCode(c, errCode)
-	errorResponse := NewErrorResponse(errCode, Err.Error())
+	message, details := utility.PublicErrorMessage(Err, utility.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))...)
+	errorResponse := NewErrorResponse(errCode, message, details...)
 	body := fiber.Map{"error": errorResponse}
 	return c.Status(httpStatus).JSON(body)
 }
@@ -230,13 +231,14 @@ func dataflowO11YLogger() dataflow.Middleware {
 			c := ingress.RawInfra.(*fiber.Ctx)
 			ctx := c.UserContext()
 
-			logger := pkg.Logger().CtxGetLogger(ctx).With(
+			ctx = wlog.CtxWithAttrs(ctx,
 				slog.Any("dataflow", slog.GroupValue(
 					slog.String("subject", ingress.Subject),
 				)),
 			)
+			logger := pkg.Logger().CtxGetLogger(ctx)
 
-			c.SetUserContext(pkg.Logger().CtxWithLogger(ctx, logger))
+			c.SetUserContext(ctx)
 			ingress.RawInfra = c
 
 			err := next(ingress, dep)
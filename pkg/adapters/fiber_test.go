@@ -0,0 +1,84 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+	"github.com/KScaesar/go-layout/pkg/utility/wgin"
+)
+
+// TestHandleErrorByFiber_parityWithGin ensures the client gets the same response from fiber and gin
+func TestHandleErrorByFiber_parityWithGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		err      error
+		language string
+		code     int
+	}{
+		{
+			name: "wrapped custom error",
+			err:  fmt.Errorf("xxx_service: %w", pkg.ErrInvalidEmail),
+			code: http.StatusBadRequest,
+		},
+		{
+			name:     "translation and details",
+			err:      utility.ErrorWithDetails(pkg.ErrInvalidParam, utility.ErrorDetail{Field: "email", Message: "invalid format"}),
+			language: "zh-TW,en;q=0.8",
+			code:     http.StatusBadRequest,
+		},
+		{
+			name: "params",
+			err:  utility.ErrorWithParams(pkg.ErrInvalidPassword, utility.ErrorParams{"min": 8, "max": 64}),
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "unknown error",
+			err:  errors.New("sql: connection refused"),
+			code: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ginRouter := gin.New()
+			ginRouter.Use(wgin.HandleError(pkg.Logger(), pkg.ErrSystem))
+			ginRouter.GET("/", func(c *gin.Context) {
+				c.Error(tt.err)
+			})
+
+			fiberRouter := fiber.New(fiber.Config{ErrorHandler: HandleErrorByFiber})
+			fiberRouter.Get("/", func(c *fiber.Ctx) error {
+				return tt.err
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.language)
+			ginRecorder := httptest.NewRecorder()
+			ginRouter.ServeHTTP(ginRecorder, req)
+
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.language)
+			fiberResp, err := fiberRouter.Test(req)
+			require.NoError(t, err)
+			fiberBody, err := io.ReadAll(fiberResp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.code, ginRecorder.Code)
+			assert.Equal(t, tt.code, fiberResp.StatusCode)
+			assert.JSONEq(t, string(fiberBody), ginRecorder.Body.String())
+		})
+	}
+}
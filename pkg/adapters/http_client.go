@@ -3,11 +3,15 @@ package adapters
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 func NewHttpClient() *http.Client {
@@ -22,14 +26,27 @@ func NewHttpClient() *http.Client {
 
 //
 
-// ConvertErrorFromHttpClient 還不知道會有怎樣的 http error, 所以只先定義預設錯誤
+// ConvertErrorFromHttpClient 連線失敗或逾時, 視為外部服務異常, 可以重試
 func ConvertErrorFromHttpClient(err error) error {
+	var netErr net.Error
 	switch {
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("server invoke server issue: %w: %w", err, pkg.ErrSystem)
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("server invoke server issue: %w: %w", err, pkg.ErrExternalService)
 	default:
 		return fmt.Errorf("server invoke server issue: %w", pkg.ErrSystem)
 	}
 }
 
+// ConvertErrorFromHttpStatus 5xx 及 429 視為外部服務異常, 可以重試
+func ConvertErrorFromHttpStatus(code int) error {
+	if code >= http.StatusInternalServerError || code == http.StatusTooManyRequests {
+		return fmt.Errorf("call external service but http status code = %v: %w", code, pkg.ErrExternalService)
+	}
+	return fmt.Errorf("call external service but http status code = %v: %w", code, pkg.ErrSystem)
+}
+
 //
 
 func GetHttpJsonBodyByType[T any](
@@ -42,6 +59,22 @@ func GetHttpJsonBodyByType[T any](
 	endpoint string,
 ) (view T, Err error) {
 
+	// GET is idempotent, the retry policy is decided by error
+	Err = utility.RetryByError(ctx, 3, 100*time.Millisecond, func() error {
+		var err error
+		view, err = getHttpJsonBody[T](client, logger, ctx, endpoint)
+		return err
+	})
+	return
+}
+
+func getHttpJsonBody[T any](
+	client *http.Client,
+	logger *slog.Logger,
+	ctx context.Context,
+	endpoint string,
+) (view T, Err error) {
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		logger.Error(err.Error(), slog.Any("cause", http.NewRequestWithContext))
@@ -60,7 +93,7 @@ func GetHttpJsonBodyByType[T any](
 
 	code := response.StatusCode
 	if code != http.StatusOK {
-		Err = fmt.Errorf("call external service but http status code != 200: %w", pkg.ErrSystem)
+		Err = ConvertErrorFromHttpStatus(code)
 		return
 	}
 
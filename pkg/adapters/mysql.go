@@ -1,35 +1,79 @@
 package adapters
 
 import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
+	driver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 
 	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
 
 	"gorm.io/gorm"
 )
 
-func NewMySqlGorm(conf *pkg.MySql) (*gorm.DB, error) {
-	db, err := gorm.Open(mysql.Open(conf.DSN()), &gorm.Config{
-		Logger:                                   nil,
+var (
+	GormO11YMetric   = utility.NewGormO11YMetric(pkg.Version().ServiceName)
+	GormTxO11YMetric = utility.NewTxO11YMetric(pkg.Version().ServiceName)
+)
+
+func NewGormTxOptions(conf *pkg.Config) []utility.GormTxOption {
+	return []utility.GormTxOption{
+		utility.WithTxRetry(conf.MySql.TxMaxAttempts, conf.MySql.TxBackoff),
+		utility.WithTxMetric(GormTxO11YMetric),
+	}
+}
+
+// NewMySqlGorm returns cleanup which closes the primary and replicas,
+// the caller owns the cleanup, e.g. the infra hook of inject.NewLifecycle.
+func NewMySqlGorm(conf *pkg.MySql) (*gorm.DB, func(), error) {
+	dialector, resolver, err := newMySqlDialector(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:                                   utility.NewGormLogger(pkg.Logger(), conf.SlowThreshold),
 		NowFunc:                                  nil,
 		DryRun:                                   false,
 		DisableForeignKeyConstraintWhenMigrating: false,
 		IgnoreRelationshipsWhenMigrating:         false,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("connect mysql: %w", err)
+		if resolver != nil {
+			resolver.Close()
+		}
+		return nil, nil, fmt.Errorf("connect mysql: %w", err)
+	}
+
+	err = db.Use(utility.NewGormO11YPlugin(GormO11YMetric, conf.QueryTimeout))
+	if err != nil {
+		CloseMySqlGorm(db)
+		return nil, nil, fmt.Errorf("use gorm o11y plugin: %w", err)
 	}
 
 	pingDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("get pingDB: %w", err)
+		CloseMySqlGorm(db)
+		return nil, nil, fmt.Errorf("get pingDB: %w", err)
+	}
+	conf.SetConnPool(pingDB)
+	err = GormO11YMetric.RegisterDBStats("primary", pingDB)
+	if err != nil {
+		CloseMySqlGorm(db)
+		return nil, nil, fmt.Errorf("register mysql primary stats: %w", err)
 	}
 
 	err = pingDB.Ping()
 	if err != nil {
-		return nil, fmt.Errorf("ping mysql: %w", err)
+		CloseMySqlGorm(db)
+		return nil, nil, fmt.Errorf("ping mysql: %w", err)
 	}
 
 	if conf.Debug {
@@ -37,13 +81,113 @@ func NewMySqlGorm(conf *pkg.MySql) (*gorm.DB, error) {
 	}
 
 	id := fmt.Sprintf("mysql(%p)", db)
-	pkg.Shutdown().AddPriorityShutdownAction(2, id, func() error {
-		stdDB, err := db.DB()
+	err = pkg.HealthRegistry().Register(utility.HealthChecker{
+		Name:     id,
+		Timeout:  time.Second,
+		Critical: true,
+		Check:    pingDB.PingContext,
+	})
+	if err != nil {
+		CloseMySqlGorm(db)
+		return nil, nil, fmt.Errorf("register mysql health: %w", err)
+	}
+	if resolver != nil {
+		// the failed replica only degrades the report, the queries fall back to the primary
+		err = pkg.HealthRegistry().Register(utility.HealthChecker{
+			Name:     id + "-replicas",
+			Timeout:  time.Second,
+			Critical: false,
+			Check:    resolver.Probe,
+		})
 		if err != nil {
-			return fmt.Errorf("get stdDB: %w", err)
+			pkg.HealthRegistry().Unregister(id)
+			CloseMySqlGorm(db)
+			return nil, nil, fmt.Errorf("register mysql replicas health: %w", err)
 		}
-		return stdDB.Close()
-	})
+		resolver.Watch(cmp.Or(conf.ProbeInterval, 5*time.Second))
+	}
+
+	// sql.DB.Close waits for the running queries
+	cleanup := func() {
+		err := CloseMySqlGorm(db)
+		if err != nil {
+			pkg.Logger().Slog().Error("close mysql failed", slog.String("id", id), slog.Any("err", err))
+		}
+	}
+	return db, cleanup, nil
+}
 
-	return db, nil
+// CloseMySqlGorm closes the primary and replicas, it is idempotent.
+func CloseMySqlGorm(db *gorm.DB) error {
+	if resolver, ok := db.Config.ConnPool.(*utility.DBResolver); ok {
+		return resolver.Close()
+	}
+	stdDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("get stdDB: %w", err)
+	}
+	return stdDB.Close()
+}
+
+// newMySqlDialector uses utility.DBResolver when replicas are declared,
+// otherwise it connects to the primary only.
+//
+// The replicas are probed before gorm.Open, since the dialector queries the server version by a read-only statement,
+// the replica which is down at boot is evicted instead of failing the startup.
+func newMySqlDialector(conf *pkg.MySql) (gorm.Dialector, *utility.DBResolver, error) {
+	if len(conf.Replicas) == 0 {
+		return mysql.Open(conf.DSN()), nil, nil
+	}
+
+	primary, err := sql.Open("mysql", conf.DSN())
+	if err != nil {
+		return nil, nil, fmt.Errorf("open mysql primary: %w", err)
+	}
+
+	replicas := make([]*sql.DB, 0, len(conf.Replicas))
+	closeOpened := func() {
+		primary.Close()
+		for _, opened := range replicas {
+			opened.Close()
+		}
+	}
+	for i, replica := range conf.Replicas {
+		db, err := sql.Open("mysql", conf.ReplicaDSN(replica))
+		if err != nil {
+			closeOpened()
+			return nil, nil, fmt.Errorf("open mysql replica %v:%v: %w", replica.Host, replica.Port, err)
+		}
+		replicas = append(replicas, db)
+
+		conf.SetConnPool(db)
+		err = GormO11YMetric.RegisterDBStats(fmt.Sprintf("replica-%d", i), db)
+		if err != nil {
+			closeOpened()
+			return nil, nil, fmt.Errorf("register mysql replica %v:%v stats: %w", replica.Host, replica.Port, err)
+		}
+	}
+
+	resolver := utility.NewDBResolver(primary, replicas, conf.ReplicaPolicy)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resolver.Probe(ctx)
+	return mysql.New(mysql.Config{Conn: resolver}), resolver, nil
+}
+
+//
+
+const mysqlDuplicateEntry = 1062
+
+// ConvertErrorFromMySQL keeps the original error in the chain,
+// so the logs still show the statement error.
+func ConvertErrorFromMySQL(err error) error {
+	var myErr *driver.MySQLError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", pkg.ErrNotExists, err)
+	case errors.As(err, &myErr) && myErr.Number == mysqlDuplicateEntry:
+		return fmt.Errorf("%w: %w", pkg.ErrExists, err)
+	default:
+		return fmt.Errorf("%w: %w", pkg.ErrDatabase, err)
+	}
 }
@@ -4,6 +4,8 @@ func NewMessageProducer() {
 
 }
 
+// NewMessageConsumer
+// consumer 實作之後, 必須向 pkg.HealthRegistry 註冊 HealthChecker, 回報 broker 連線狀態
 func NewMessageConsumer() {
 
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -12,17 +13,37 @@ import (
 	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
-func NewRedis(conf *pkg.Redis) (*redis.Client, error) {
+// NewRedis returns cleanup which closes the client,
+// the caller owns the cleanup, e.g. the infra hook of inject.NewLifecycle.
+func NewRedis(conf *pkg.Redis) (*redis.Client, func(), error) {
 	client := redis.NewClient(newRedisOptions(conf, 0))
 
 	err := client.Ping(context.Background()).Err()
 	if err != nil {
-		return nil, fmt.Errorf("ping redis: %w", err)
+		return nil, nil, fmt.Errorf("ping redis: %w", err)
 	}
 
 	id := fmt.Sprintf("redis(%p)", client)
-	pkg.Shutdown().AddPriorityShutdownAction(2, id, client.Close)
-	return client, nil
+	err = pkg.HealthRegistry().Register(utility.HealthChecker{
+		Name:     id,
+		Timeout:  time.Second,
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	})
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("register redis health: %w", err)
+	}
+
+	cleanup := func() {
+		err := client.Close()
+		if err != nil {
+			pkg.Logger().Slog().Error("close redis failed", slog.String("id", id), slog.Any("err", err))
+		}
+	}
+	return client, cleanup, nil
 }
 
 func newRedisOptions(conf *pkg.Redis, db int) (opt *redis.Options) {
@@ -68,7 +89,9 @@ func GetRedisStringByType[T any](
 
 	bData, err := client.Get(ctx, key).Bytes()
 	if err != nil {
-		logger.Error("redis get by string", slog.Any("err", err))
+		if !errors.Is(err, redis.Nil) {
+			logger.Error("redis get by string", slog.Any("err", err))
+		}
 		Err = ConvertErrorFromRedis(err)
 		return
 	}
@@ -1,22 +1,217 @@
 package app
 
 import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 func RegisterUser(req *RegisterUserRequest) (*User, error) {
-	user := User{}
+	err := validateUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	err = validateEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	password, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := User{
+		Id:        utility.NewUlid(),
+		Username:  req.Username,
+		Email:     req.Email,
+		Password:  password,
+		Status:    UserStatusActive,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
 	return &user, nil
 }
 
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "active"
+	UserStatusDisabled UserStatus = "disabled"
+	UserStatusDeleted  UserStatus = "deleted"
+)
+
 type User struct {
+	Id        string
+	Username  string
+	Email     string
+	Password  string // bcrypt hash
+	Status    UserStatus
+	Version   uint64 // optimistic locking, it is increased by repository
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// ByUpdated records the changed columns, repository only writes them, e.g. {"email": "x@y.z"}
 	ByUpdated utility.MapData `gorm:"-"`
 }
 
 func (user *User) ResetPassword(req *ResetUserPasswordRequest) error {
+	err := user.checkAvailable()
+	if err != nil {
+		return err
+	}
+
+	password, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = password
+	user.markUpdated("password", password)
 	return nil
 }
 
+// ChangePassword requires the old password, ResetPassword does not.
+func (user *User) ChangePassword(req *UpdateUserPasswordRequest) error {
+	err := user.VerifyPassword(req.OldPassword)
+	if err != nil {
+		return err
+	}
+	return user.ResetPassword(&ResetUserPasswordRequest{UserId: user.Id, Password: req.NewPassword})
+}
+
+// VerifyPassword compares the hash before checking status,
+// so the status of account is not revealed to the caller without the correct password.
+func (user *User) VerifyPassword(password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return pkg.ErrIncorrectPassword
+	}
+	if err != nil {
+		return fmt.Errorf("compare password: %v: %w", err, pkg.ErrSystem)
+	}
+	return user.checkAvailable()
+}
+
+// dummyPasswordHash is generated on first use, since bcrypt is slow on purpose
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// VerifyUnknownUserPassword is used when the username does not exist,
+// it costs the same time as VerifyPassword, so the response time does not reveal the existence of username.
+func VerifyUnknownUserPassword(password string) error {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+	return pkg.ErrIncorrectPassword
+}
+
 func (user *User) UpdateInfo(req *UpdateUserInfoRequest) error {
+	err := user.checkAvailable()
+	if err != nil {
+		return err
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		err := validateUsername(*req.Username)
+		if err != nil {
+			return err
+		}
+		user.Username = *req.Username
+		user.markUpdated("username", user.Username)
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		err := validateEmail(*req.Email)
+		if err != nil {
+			return err
+		}
+		user.Email = *req.Email
+		user.markUpdated("email", user.Email)
+	}
 	return nil
 }
+
+func (user *User) Delete() error {
+	if user.Status == UserStatusDeleted {
+		return pkg.ErrNotExists
+	}
+	user.Status = UserStatusDeleted
+	user.markUpdated("status", user.Status)
+	return nil
+}
+
+func (user *User) checkAvailable() error {
+	switch user.Status {
+	case UserStatusDeleted:
+		return pkg.ErrNotExists
+	case UserStatusDisabled:
+		return pkg.ErrUserDisabled
+	}
+	return nil
+}
+
+func (user *User) markUpdated(column string, value any) {
+	user.UpdatedAt = time.Now()
+	data := user.ByUpdated.MustOk()
+	data.Set(column, value)
+	data.Set("updated_at", user.UpdatedAt)
+}
+
+//
+
+const (
+	usernameMinLength = 3
+	usernameMaxLength = 64
+	passwordMinLength = 8
+	passwordMaxLength = 72 // bcrypt only uses the first 72 bytes
+)
+
+func validateUsername(username string) error {
+	length := utf8.RuneCountInString(username)
+	if length < usernameMinLength || length > usernameMaxLength {
+		return utility.ErrorWithDetails(pkg.ErrInvalidParam, utility.ErrorDetail{
+			Field:   "username",
+			Message: fmt.Sprintf("length must be %v to %v", usernameMinLength, usernameMaxLength),
+		})
+	}
+
+	for _, char := range username {
+		if unicode.IsUpper(char) {
+			return nil
+		}
+	}
+	return pkg.ErrInvalidUsername
+}
+
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return pkg.ErrInvalidEmail
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < passwordMinLength || len(password) > passwordMaxLength {
+		return "", utility.ErrorWithParams(pkg.ErrInvalidPassword, utility.ErrorParams{
+			"min": passwordMinLength,
+			"max": passwordMaxLength,
+		})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %v: %w", err, pkg.ErrSystem)
+	}
+	return string(hash), nil
+}
@@ -1,33 +1,57 @@
 package app
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
 	"github.com/KScaesar/go-layout/pkg/utility/dataflow"
 )
 
 // write
 
 type RegisterUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
+// UpdateUserInfoRequest nil indicates the field is not changed
 type UpdateUserInfoRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
 }
 
 type UpdateUserPasswordRequest struct {
+	UserId      string `json:"-"`
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
 }
 
 type ResetUserPasswordRequest struct {
+	UserId   string `json:"user_id"`
+	Password string `json:"password"`
 }
 
 type DeleteUserRequest struct {
+	UserId string `json:"user_id"`
 }
 
 // event
 
 func NewRegisteredUserEvent(user *User) *dataflow.Message {
-	return dataflow.NewBodyEgress("user.registered", &RegisteredUserEvent{})
+	return dataflow.NewBodyEgress("user.registered", &RegisteredUserEvent{
+		UserId:   user.Id,
+		Username: user.Username,
+		Email:    user.Email,
+	})
 }
 
 type RegisteredUserEvent struct {
+	UserId   string `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
 }
 
 // read
@@ -37,14 +61,108 @@ type LoginUserRequest struct {
 	Password string `json:"password"`
 }
 
+type LoginUserResponse struct {
+	User  UserResponse  `json:"user"`
+	Token TokenResponse `json:"token"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutUserRequest revokes both tokens, AccessToken comes from the header Authorization
+type LogoutUserRequest struct {
+	AccessToken  string `json:"-"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // the seconds of access token
+}
+
+type UserSortBy string
+
+const (
+	UserSortByCreatedAt UserSortBy = "created_at"
+	UserSortByUsername  UserSortBy = "username"
+)
+
+const (
+	queryMultiUserDefaultLimit = 20
+	queryMultiUserMaxLimit     = 100
+)
+
+// QueryMultiUserRequest uses keyset pagination,
+// Cursor is the NextCursor of the previous page, empty indicates the first page.
 type QueryMultiUserRequest struct {
+	Username string     `form:"username" query:"username"` // prefix match
+	Email    string     `form:"email" query:"email"`
+	Status   UserStatus `form:"status" query:"status"` // empty indicates all users except deleted
+
+	SortBy UserSortBy `form:"sort_by" query:"sort_by"` // default is created_at
+	Desc   bool       `form:"desc" query:"desc"`
+	Cursor string     `form:"cursor" query:"cursor"`
+	Limit  int        `form:"limit" query:"limit"` // default is 20, max is 100
 }
 
-type MultiUserResponse []UserResponse
+// Validate fills the default values, the repository assumes the request is validated.
+func (req *QueryMultiUserRequest) Validate() error {
+	switch req.Status {
+	case "", UserStatusActive, UserStatusDisabled, UserStatusDeleted:
+	default:
+		return utility.ErrorWithDetails(pkg.ErrInvalidParam, utility.ErrorDetail{
+			Field:   "status",
+			Message: fmt.Sprintf("unknown status %q", req.Status),
+		})
+	}
+
+	switch req.SortBy {
+	case "":
+		req.SortBy = UserSortByCreatedAt
+	case UserSortByCreatedAt, UserSortByUsername:
+	default:
+		return utility.ErrorWithDetails(pkg.ErrInvalidParam, utility.ErrorDetail{
+			Field:   "sort_by",
+			Message: fmt.Sprintf("unknown sort_by %q", req.SortBy),
+		})
+	}
+
+	switch {
+	case req.Limit == 0:
+		req.Limit = queryMultiUserDefaultLimit
+	case req.Limit < 0 || req.Limit > queryMultiUserMaxLimit:
+		return utility.ErrorWithDetails(pkg.ErrInvalidParam, utility.ErrorDetail{
+			Field:   "limit",
+			Message: fmt.Sprintf("limit must be 1 to %v", queryMultiUserMaxLimit),
+		})
+	}
+	return nil
+}
+
+type MultiUserResponse struct {
+	Users      []UserResponse `json:"users"`
+	NextCursor string         `json:"next_cursor"` // empty indicates the last page
+}
 
 func ConvertUserResponse(user *User) UserResponse {
-	return UserResponse{}
+	return UserResponse{
+		Id:        user.Id,
+		Username:  user.Username,
+		Email:     user.Email,
+		Status:    user.Status,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
 }
 
 type UserResponse struct {
+	Id        string     `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Status    UserStatus `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
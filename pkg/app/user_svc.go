@@ -2,8 +2,13 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 
-	"github.com/KScaesar/go-layout/pkg/utility/dataflow"
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
 )
 
 //go:generate mockgen -typed -package=app -destination=user_svc_mock.go -source=user_svc.go
@@ -19,6 +24,21 @@ type UserRepository interface {
 	QueryMultiUserByFilter(ctx context.Context, filter *QueryMultiUserRequest) (MultiUserResponse, error)
 }
 
+// TokenService issues and revokes the session tokens, the revoked tokens are rejected by the auth middleware.
+type TokenService interface {
+	IssueToken(ctx context.Context, principal utility.Principal) (TokenResponse, error)
+
+	// RefreshToken rotates the refresh token, the old one is revoked.
+	// check is called before the rotation, e.g. the user is still active.
+	RefreshToken(ctx context.Context, refreshToken string, check func(ctx context.Context, principal utility.Principal) error) (TokenResponse, error)
+
+	// RevokeToken ignores the tokens which are invalid, expired or not owned by the user
+	RevokeToken(ctx context.Context, userId string, tokens ...string) error
+
+	// RevokeAllTokens rejects all sessions of the user, e.g. the password is changed
+	RevokeAllTokens(ctx context.Context, userId string) error
+}
+
 type UserService interface {
 	RegisterUser(ctx context.Context, req *RegisterUserRequest) error
 	UpdateUserInfo(ctx context.Context, userId string, req *UpdateUserInfoRequest) error
@@ -26,19 +46,28 @@ type UserService interface {
 	ResetUserPassword(ctx context.Context, req *ResetUserPasswordRequest) error
 	DeleteUser(ctx context.Context, req *DeleteUserRequest) error
 
-	LoginUser(ctx context.Context, req *LoginUserRequest) (UserResponse, error)
+	LoginUser(ctx context.Context, req *LoginUserRequest) (LoginUserResponse, error)
+	RefreshToken(ctx context.Context, req *RefreshTokenRequest) (TokenResponse, error)
+	LogoutUser(ctx context.Context, req *LogoutUserRequest) error
 	QueryMultiUser(ctx context.Context, filter *QueryMultiUserRequest) (MultiUserResponse, error)
 }
 
-func NewUserUseCase(userRepo UserRepository) *UserUseCase {
+// NewUserUseCase
+//
+// The write use cases are executed by transaction,
+// so they are retried as a whole when the database reports deadlock, see utility.WithTxRetry.
+func NewUserUseCase(userRepo UserRepository, tokens TokenService, transaction utility.EasyTransaction) *UserUseCase {
 	return &UserUseCase{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		tokens:      tokens,
+		transaction: transaction,
 	}
 }
 
 type UserUseCase struct {
-	userRepo UserRepository
-	bus      dataflow.Producer
+	userRepo    UserRepository
+	tokens      TokenService
+	transaction utility.EasyTransaction
 }
 
 func (uc *UserUseCase) RegisterUser(ctx context.Context, req *RegisterUserRequest) error {
@@ -47,50 +76,170 @@ func (uc *UserUseCase) RegisterUser(ctx context.Context, req *RegisterUserReques
 		return err
 	}
 
-	err = uc.userRepo.CreteUser(ctx, user)
+	err = uc.transaction(ctx, func(ctxTX context.Context) error {
+		return uc.userRepo.CreteUser(ctxTX, user)
+	})
 	if err != nil {
 		return err
 	}
 
+	actor := "anonymous"
+	if principal, ok := utility.CtxGetPrincipal(ctx); ok {
+		actor = principal.UserId
+	}
+
 	event := NewRegisteredUserEvent(user)
-	return uc.bus.SendWithCtx(ctx, event)
+	audit := wlog.Event{
+		Actor:    actor,
+		Action:   event.Subject,
+		Resource: "user",
+		Detail:   event.Body,
+	}
+
+	emitAudit(ctx, audit, nil)
+	return nil
 }
 
 func (uc *UserUseCase) UpdateUserInfo(ctx context.Context, userId string, req *UpdateUserInfoRequest) error {
-	user, err := uc.userRepo.LockUserById(ctx, userId)
+	return uc.transaction(ctx, func(ctxTX context.Context) error {
+		user, err := uc.userRepo.LockUserById(ctxTX, userId)
+		if err != nil {
+			return err
+		}
+
+		err = user.UpdateInfo(req)
+		if err != nil {
+			return err
+		}
+
+		return uc.userRepo.UpdateUser(ctxTX, &user)
+	})
+}
+
+func (uc *UserUseCase) UpdateUserPassword(ctx context.Context, req *UpdateUserPasswordRequest) error {
+	err := uc.transaction(ctx, func(ctxTX context.Context) error {
+		user, err := uc.userRepo.LockUserById(ctxTX, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		err = user.ChangePassword(req)
+		if err != nil {
+			return err
+		}
+
+		return uc.userRepo.UpdateUser(ctxTX, &user)
+	})
 	if err != nil {
 		return err
 	}
 
-	err = user.UpdateInfo(req)
+	// the sessions which are issued by the old password are rejected
+	return uc.tokens.RevokeAllTokens(ctx, req.UserId)
+}
+
+func (uc *UserUseCase) ResetUserPassword(ctx context.Context, req *ResetUserPasswordRequest) error {
+	err := uc.transaction(ctx, func(ctxTX context.Context) error {
+		user, err := uc.userRepo.LockUserById(ctxTX, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		err = user.ResetPassword(req)
+		if err != nil {
+			return err
+		}
+
+		return uc.userRepo.UpdateUser(ctxTX, &user)
+	})
 	if err != nil {
 		return err
 	}
 
-	return uc.userRepo.UpdateUser(ctx, &user)
+	// the sessions which are issued by the old password are rejected
+	return uc.tokens.RevokeAllTokens(ctx, req.UserId)
 }
 
-func (uc *UserUseCase) UpdateUserPassword(ctx context.Context, req *UpdateUserPasswordRequest) error {
-	// TODO implement me
-	panic("implement me")
+func (uc *UserUseCase) DeleteUser(ctx context.Context, req *DeleteUserRequest) error {
+	return uc.transaction(ctx, func(ctxTX context.Context) error {
+		user, err := uc.userRepo.LockUserById(ctxTX, req.UserId)
+		if err != nil {
+			return err
+		}
+
+		err = user.Delete()
+		if err != nil {
+			return err
+		}
+
+		return uc.userRepo.DeleteUser(ctxTX, &user)
+	})
 }
 
-func (uc *UserUseCase) ResetUserPassword(ctx context.Context, req *ResetUserPasswordRequest) error {
-	// TODO implement me
-	panic("implement me")
+func (uc *UserUseCase) LoginUser(ctx context.Context, req *LoginUserRequest) (LoginUserResponse, error) {
+	audit := wlog.Event{
+		Actor:    req.Username,
+		Action:   "user.login",
+		Resource: "user",
+	}
+
+	user, err := uc.userRepo.LoginUser(ctx, req)
+	if err != nil {
+		emitAudit(ctx, audit, err)
+		return LoginUserResponse{}, err
+	}
+
+	token, err := uc.tokens.IssueToken(ctx, utility.Principal{UserId: user.Id, Username: user.Username})
+	emitAudit(ctx, audit, err)
+	if err != nil {
+		return LoginUserResponse{}, err
+	}
+	return LoginUserResponse{User: user, Token: token}, nil
 }
 
-func (uc *UserUseCase) DeleteUser(ctx context.Context, req *DeleteUserRequest) error {
-	// TODO implement me
-	panic("implement me")
+func (uc *UserUseCase) RefreshToken(ctx context.Context, req *RefreshTokenRequest) (TokenResponse, error) {
+	return uc.tokens.RefreshToken(ctx, req.RefreshToken, uc.checkActiveUser)
+}
+
+// checkActiveUser rejects the session of user which is deleted or disabled after login
+func (uc *UserUseCase) checkActiveUser(ctx context.Context, principal utility.Principal) error {
+	user, err := uc.userRepo.QueryUserById(ctx, principal.UserId)
+	if errors.Is(err, pkg.ErrNotExists) {
+		return fmt.Errorf("%w: user does not exist", pkg.ErrUnauthorized)
+	}
+	if err != nil {
+		return err
+	}
+	if user.Status != UserStatusActive {
+		return pkg.ErrUserDisabled
+	}
+	return nil
 }
 
-func (uc *UserUseCase) LoginUser(ctx context.Context, req *LoginUserRequest) (UserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+func (uc *UserUseCase) LogoutUser(ctx context.Context, req *LogoutUserRequest) error {
+	principal, ok := utility.CtxGetPrincipal(ctx)
+	if !ok {
+		return pkg.ErrUnauthorized
+	}
+	return uc.tokens.RevokeToken(ctx, principal.UserId, req.AccessToken, req.RefreshToken)
 }
 
 func (uc *UserUseCase) QueryMultiUser(ctx context.Context, filter *QueryMultiUserRequest) (MultiUserResponse, error) {
-	// TODO implement me
-	panic("implement me")
+	err := filter.Validate()
+	if err != nil {
+		return MultiUserResponse{}, err
+	}
+	return uc.userRepo.QueryMultiUserByFilter(ctx, filter)
+}
+
+// emitAudit records the result of use case,
+// the failure of audit log does not fail the use case, it is logged instead.
+func emitAudit(ctx context.Context, event wlog.Event, result error) {
+	err := pkg.EventLogger().EmitResult(ctx, event, result)
+	if err != nil {
+		pkg.Logger().CtxGetLogger(ctx).Error("emit audit event failed",
+			slog.String("action", event.Action),
+			slog.Any("err", err),
+		)
+	}
 }
@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	utility "github.com/KScaesar/go-layout/pkg/utility"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -309,6 +310,188 @@ func (c *MockUserRepositoryUpdateUserCall) DoAndReturn(f func(context.Context, *
 	return c
 }
 
+// MockTokenService is a mock of TokenService interface.
+type MockTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenServiceMockRecorder
+}
+
+// MockTokenServiceMockRecorder is the mock recorder for MockTokenService.
+type MockTokenServiceMockRecorder struct {
+	mock *MockTokenService
+}
+
+// NewMockTokenService creates a new mock instance.
+func NewMockTokenService(ctrl *gomock.Controller) *MockTokenService {
+	mock := &MockTokenService{ctrl: ctrl}
+	mock.recorder = &MockTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenService) EXPECT() *MockTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// IssueToken mocks base method.
+func (m *MockTokenService) IssueToken(ctx context.Context, principal utility.Principal) (TokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueToken", ctx, principal)
+	ret0, _ := ret[0].(TokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueToken indicates an expected call of IssueToken.
+func (mr *MockTokenServiceMockRecorder) IssueToken(ctx, principal any) *MockTokenServiceIssueTokenCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueToken", reflect.TypeOf((*MockTokenService)(nil).IssueToken), ctx, principal)
+	return &MockTokenServiceIssueTokenCall{Call: call}
+}
+
+// MockTokenServiceIssueTokenCall wrap *gomock.Call
+type MockTokenServiceIssueTokenCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockTokenServiceIssueTokenCall) Return(arg0 TokenResponse, arg1 error) *MockTokenServiceIssueTokenCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockTokenServiceIssueTokenCall) Do(f func(context.Context, utility.Principal) (TokenResponse, error)) *MockTokenServiceIssueTokenCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockTokenServiceIssueTokenCall) DoAndReturn(f func(context.Context, utility.Principal) (TokenResponse, error)) *MockTokenServiceIssueTokenCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// RefreshToken mocks base method.
+func (m *MockTokenService) RefreshToken(ctx context.Context, refreshToken string, check func(context.Context, utility.Principal) error) (TokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, refreshToken, check)
+	ret0, _ := ret[0].(TokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockTokenServiceMockRecorder) RefreshToken(ctx, refreshToken, check any) *MockTokenServiceRefreshTokenCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockTokenService)(nil).RefreshToken), ctx, refreshToken, check)
+	return &MockTokenServiceRefreshTokenCall{Call: call}
+}
+
+// MockTokenServiceRefreshTokenCall wrap *gomock.Call
+type MockTokenServiceRefreshTokenCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockTokenServiceRefreshTokenCall) Return(arg0 TokenResponse, arg1 error) *MockTokenServiceRefreshTokenCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockTokenServiceRefreshTokenCall) Do(f func(context.Context, string, func(context.Context, utility.Principal) error) (TokenResponse, error)) *MockTokenServiceRefreshTokenCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockTokenServiceRefreshTokenCall) DoAndReturn(f func(context.Context, string, func(context.Context, utility.Principal) error) (TokenResponse, error)) *MockTokenServiceRefreshTokenCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// RevokeAllTokens mocks base method.
+func (m *MockTokenService) RevokeAllTokens(ctx context.Context, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllTokens", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllTokens indicates an expected call of RevokeAllTokens.
+func (mr *MockTokenServiceMockRecorder) RevokeAllTokens(ctx, userId any) *MockTokenServiceRevokeAllTokensCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllTokens", reflect.TypeOf((*MockTokenService)(nil).RevokeAllTokens), ctx, userId)
+	return &MockTokenServiceRevokeAllTokensCall{Call: call}
+}
+
+// MockTokenServiceRevokeAllTokensCall wrap *gomock.Call
+type MockTokenServiceRevokeAllTokensCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockTokenServiceRevokeAllTokensCall) Return(arg0 error) *MockTokenServiceRevokeAllTokensCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockTokenServiceRevokeAllTokensCall) Do(f func(context.Context, string) error) *MockTokenServiceRevokeAllTokensCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockTokenServiceRevokeAllTokensCall) DoAndReturn(f func(context.Context, string) error) *MockTokenServiceRevokeAllTokensCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// RevokeToken mocks base method.
+func (m *MockTokenService) RevokeToken(ctx context.Context, userId string, tokens ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, userId}
+	for _, a := range tokens {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeToken", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockTokenServiceMockRecorder) RevokeToken(ctx, userId any, tokens ...any) *MockTokenServiceRevokeTokenCall {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, userId}, tokens...)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockTokenService)(nil).RevokeToken), varargs...)
+	return &MockTokenServiceRevokeTokenCall{Call: call}
+}
+
+// MockTokenServiceRevokeTokenCall wrap *gomock.Call
+type MockTokenServiceRevokeTokenCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockTokenServiceRevokeTokenCall) Return(arg0 error) *MockTokenServiceRevokeTokenCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockTokenServiceRevokeTokenCall) Do(f func(context.Context, string, ...string) error) *MockTokenServiceRevokeTokenCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockTokenServiceRevokeTokenCall) DoAndReturn(f func(context.Context, string, ...string) error) *MockTokenServiceRevokeTokenCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // MockUserService is a mock of UserService interface.
 type MockUserService struct {
 	ctrl     *gomock.Controller
@@ -371,10 +554,10 @@ func (c *MockUserServiceDeleteUserCall) DoAndReturn(f func(context.Context, *Del
 }
 
 // LoginUser mocks base method.
-func (m *MockUserService) LoginUser(ctx context.Context, req *LoginUserRequest) (UserResponse, error) {
+func (m *MockUserService) LoginUser(ctx context.Context, req *LoginUserRequest) (LoginUserResponse, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "LoginUser", ctx, req)
-	ret0, _ := ret[0].(UserResponse)
+	ret0, _ := ret[0].(LoginUserResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -392,19 +575,57 @@ type MockUserServiceLoginUserCall struct {
 }
 
 // Return rewrite *gomock.Call.Return
-func (c *MockUserServiceLoginUserCall) Return(arg0 UserResponse, arg1 error) *MockUserServiceLoginUserCall {
+func (c *MockUserServiceLoginUserCall) Return(arg0 LoginUserResponse, arg1 error) *MockUserServiceLoginUserCall {
 	c.Call = c.Call.Return(arg0, arg1)
 	return c
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockUserServiceLoginUserCall) Do(f func(context.Context, *LoginUserRequest) (UserResponse, error)) *MockUserServiceLoginUserCall {
+func (c *MockUserServiceLoginUserCall) Do(f func(context.Context, *LoginUserRequest) (LoginUserResponse, error)) *MockUserServiceLoginUserCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUserServiceLoginUserCall) DoAndReturn(f func(context.Context, *LoginUserRequest) (LoginUserResponse, error)) *MockUserServiceLoginUserCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// LogoutUser mocks base method.
+func (m *MockUserService) LogoutUser(ctx context.Context, req *LogoutUserRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutUser", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutUser indicates an expected call of LogoutUser.
+func (mr *MockUserServiceMockRecorder) LogoutUser(ctx, req any) *MockUserServiceLogoutUserCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutUser", reflect.TypeOf((*MockUserService)(nil).LogoutUser), ctx, req)
+	return &MockUserServiceLogoutUserCall{Call: call}
+}
+
+// MockUserServiceLogoutUserCall wrap *gomock.Call
+type MockUserServiceLogoutUserCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUserServiceLogoutUserCall) Return(arg0 error) *MockUserServiceLogoutUserCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUserServiceLogoutUserCall) Do(f func(context.Context, *LogoutUserRequest) error) *MockUserServiceLogoutUserCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockUserServiceLoginUserCall) DoAndReturn(f func(context.Context, *LoginUserRequest) (UserResponse, error)) *MockUserServiceLoginUserCall {
+func (c *MockUserServiceLogoutUserCall) DoAndReturn(f func(context.Context, *LogoutUserRequest) error) *MockUserServiceLogoutUserCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
@@ -448,6 +669,45 @@ func (c *MockUserServiceQueryMultiUserCall) DoAndReturn(f func(context.Context,
 	return c
 }
 
+// RefreshToken mocks base method.
+func (m *MockUserService) RefreshToken(ctx context.Context, req *RefreshTokenRequest) (TokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, req)
+	ret0, _ := ret[0].(TokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockUserServiceMockRecorder) RefreshToken(ctx, req any) *MockUserServiceRefreshTokenCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockUserService)(nil).RefreshToken), ctx, req)
+	return &MockUserServiceRefreshTokenCall{Call: call}
+}
+
+// MockUserServiceRefreshTokenCall wrap *gomock.Call
+type MockUserServiceRefreshTokenCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUserServiceRefreshTokenCall) Return(arg0 TokenResponse, arg1 error) *MockUserServiceRefreshTokenCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUserServiceRefreshTokenCall) Do(f func(context.Context, *RefreshTokenRequest) (TokenResponse, error)) *MockUserServiceRefreshTokenCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUserServiceRefreshTokenCall) DoAndReturn(f func(context.Context, *RefreshTokenRequest) (TokenResponse, error)) *MockUserServiceRefreshTokenCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // RegisterUser mocks base method.
 func (m *MockUserService) RegisterUser(ctx context.Context, req *RegisterUserRequest) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+func TestUserUseCase_RefreshToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    UserResponse
+		err     error
+		wantErr error
+	}{
+		{
+			name: "active user",
+			user: UserResponse{Id: "u1", Status: UserStatusActive},
+		},
+		{
+			name:    "disabled user",
+			user:    UserResponse{Id: "u1", Status: UserStatusDisabled},
+			wantErr: pkg.ErrUserDisabled,
+		},
+		{
+			name:    "deleted user",
+			err:     pkg.ErrNotExists,
+			wantErr: pkg.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			userRepo := NewMockUserRepository(ctrl)
+			userRepo.EXPECT().
+				QueryUserById(gomock.Any(), "u1").
+				Return(tt.user, tt.err)
+
+			tokens := NewMockTokenService(ctrl)
+			tokens.EXPECT().
+				RefreshToken(gomock.Any(), "refresh-token", gomock.Any()).
+				DoAndReturn(func(ctx context.Context, _ string, check func(context.Context, utility.Principal) error) (TokenResponse, error) {
+					err := check(ctx, utility.Principal{UserId: "u1"})
+					if err != nil {
+						return TokenResponse{}, err
+					}
+					return TokenResponse{AccessToken: "access-token"}, nil
+				})
+
+			uc := NewUserUseCase(userRepo, tokens, utility.NonEasyTransaction())
+			resp, err := uc.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: "refresh-token"})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "access-token", resp.AccessToken)
+		})
+	}
+}
+
+func TestUserUseCase_UpdateUserPassword(t *testing.T) {
+	user, err := RegisterUser(&RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	userRepo := NewMockUserRepository(ctrl)
+	userRepo.EXPECT().LockUserById(gomock.Any(), user.Id).Return(*user, nil).Times(2)
+	userRepo.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Return(nil)
+
+	// the existing sessions are revoked only after the password is changed
+	tokens := NewMockTokenService(ctrl)
+	tokens.EXPECT().RevokeAllTokens(gomock.Any(), user.Id).Return(nil).Times(1)
+
+	uc := NewUserUseCase(userRepo, tokens, utility.NonEasyTransaction())
+	err = uc.UpdateUserPassword(context.Background(), &UpdateUserPasswordRequest{UserId: user.Id, OldPassword: "wrong-password", NewPassword: "87654321"})
+	assert.ErrorIs(t, err, pkg.ErrIncorrectPassword)
+
+	err = uc.UpdateUserPassword(context.Background(), &UpdateUserPasswordRequest{UserId: user.Id, OldPassword: "12345678", NewPassword: "87654321"})
+	assert.NoError(t, err)
+}
+
+func TestUserUseCase_RegisterUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := NewMockUserRepository(ctrl)
+	userRepo.EXPECT().
+		CreteUser(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, user *User) error {
+			assert.Equal(t, "Caesar", user.Username)
+			assert.Equal(t, UserStatusActive, user.Status)
+			return nil
+		}).
+		Times(1)
+
+	uc := NewUserUseCase(userRepo, NewMockTokenService(ctrl), utility.NonEasyTransaction())
+
+	err := uc.RegisterUser(context.Background(), &RegisterUserRequest{})
+	assert.ErrorIs(t, err, pkg.ErrInvalidParam)
+
+	err = uc.RegisterUser(context.Background(), &RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg"
+)
+
+func TestRegisterUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     RegisterUserRequest
+		wantErr error
+	}{
+		{
+			name: "ok",
+			req:  RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"},
+		},
+		{
+			name:    "username without uppercase",
+			req:     RegisterUserRequest{Username: "caesar", Email: "caesar@example.com", Password: "12345678"},
+			wantErr: pkg.ErrInvalidUsername,
+		},
+		{
+			name:    "username too short",
+			req:     RegisterUserRequest{Username: "Ca", Email: "caesar@example.com", Password: "12345678"},
+			wantErr: pkg.ErrInvalidParam,
+		},
+		{
+			name:    "invalid email",
+			req:     RegisterUserRequest{Username: "Caesar", Email: "Caesar <caesar@example.com>", Password: "12345678"},
+			wantErr: pkg.ErrInvalidEmail,
+		},
+		{
+			name:    "password too short",
+			req:     RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "1234"},
+			wantErr: pkg.ErrInvalidPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := RegisterUser(&tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, user.Id)
+			assert.NotEqual(t, tt.req.Password, user.Password)
+			assert.Equal(t, UserStatusActive, user.Status)
+			assert.NoError(t, user.VerifyPassword(tt.req.Password))
+		})
+	}
+}
+
+func TestUser_ChangePassword(t *testing.T) {
+	user, err := RegisterUser(&RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"})
+	require.NoError(t, err)
+
+	err = user.ChangePassword(&UpdateUserPasswordRequest{OldPassword: "wrong-password", NewPassword: "87654321"})
+	assert.ErrorIs(t, err, pkg.ErrIncorrectPassword)
+	assert.Empty(t, user.ByUpdated)
+
+	err = user.ChangePassword(&UpdateUserPasswordRequest{OldPassword: "12345678", NewPassword: "87654321"})
+	require.NoError(t, err)
+	assert.NoError(t, user.VerifyPassword("87654321"))
+	assert.Contains(t, user.ByUpdated, "password")
+	assert.Contains(t, user.ByUpdated, "updated_at")
+}
+
+func TestUser_VerifyPassword(t *testing.T) {
+	user, err := RegisterUser(&RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"})
+	require.NoError(t, err)
+	user.Status = UserStatusDisabled
+
+	// the status is not revealed without the correct password
+	assert.ErrorIs(t, user.VerifyPassword("wrong-password"), pkg.ErrIncorrectPassword)
+	assert.ErrorIs(t, user.VerifyPassword("12345678"), pkg.ErrUserDisabled)
+
+	assert.ErrorIs(t, VerifyUnknownUserPassword("12345678"), pkg.ErrIncorrectPassword)
+}
+
+func TestUser_UpdateInfo(t *testing.T) {
+	user, err := RegisterUser(&RegisterUserRequest{Username: "Caesar", Email: "caesar@example.com", Password: "12345678"})
+	require.NoError(t, err)
+
+	email := "new@example.com"
+	err = user.UpdateInfo(&UpdateUserInfoRequest{Email: &email})
+	require.NoError(t, err)
+	assert.Equal(t, email, user.ByUpdated["email"])
+	assert.NotContains(t, user.ByUpdated, "username")
+
+	require.NoError(t, user.Delete())
+	err = user.UpdateInfo(&UpdateUserInfoRequest{Email: &email})
+	assert.ErrorIs(t, err, pkg.ErrNotExists)
+	assert.ErrorIs(t, user.Delete(), pkg.ErrNotExists)
+}
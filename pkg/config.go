@@ -1,12 +1,15 @@
 package pkg
 
 import (
+	"context"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -18,18 +21,83 @@ func MustLoadConfig() *Config {
 	const defaultPath = "./configs/config.yml"
 
 	filePath := flag.String("conf", defaultPath, "Path to the configuration file")
+	errFormat := flag.String("errors", "", "Print the error catalog (json|openapi) and exit")
 	flag.Parse()
 
+	if *errFormat != "" {
+		bData, err := ErrorRegistry().MarshalCatalog(*errFormat)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(bData))
+		os.Exit(0)
+	}
+
 	logger := Logger().Slog()
 
-	conf, err := utility.LoadLocalConfigFromMultiSource[Config](yaml.Unmarshal, *filePath, logger)
+	conf, path, err := utility.LoadLocalConfigFromMultiSource[Config](yaml.Unmarshal, *filePath, logger)
 	if err != nil {
 		logger.Error("load config fail", slog.Any("err", err))
 		os.Exit(1)
 	}
+	conf.path = path
 	return conf
 }
 
+// WatchConfig reloads the config which supports hot reload when the config file is changed.
+//
+// Currently, only Logger and Filepath.Logger support hot reload.
+//
+// Logger.Level is applied only when it is changed in the file,
+// so the level changed by admin endpoint isn't overridden by other unrelated edits.
+func WatchConfig(conf *Config) {
+	if conf.path == "" {
+		return
+	}
+
+	lastLevel := conf.Logger.Level
+
+	stop := utility.WatchLocalFile(conf.path, 5*time.Second, func() {
+		logger := Logger().Slog()
+
+		newConf, err := utility.LoadLocalFile[Config](yaml.Unmarshal, conf.path)
+		if err != nil {
+			logger.Error("watch config fail", slog.Any("err", err))
+			return
+		}
+
+		level := newConf.Logger.Level
+		if equalLevel(level, lastLevel) {
+			newConf.Logger.Level = nil
+		}
+
+		err = Logger().Reload(newConf.Filepath.Logger, &newConf.Logger)
+		EventLogger().EmitResult(context.Background(), wlog.Event{
+			Actor:    "config_watcher",
+			Action:   "logger.config.reload",
+			Resource: "logger",
+			Detail:   map[string]any{"path": conf.path},
+		}, err)
+		if err != nil {
+			logger.Error("reload logger config fail", slog.Any("err", err))
+			return
+		}
+		lastLevel = level
+		Logger().SetStdDefaultLevel()
+		logger.Info("reload logger config", slog.String("path", conf.path))
+	})
+
+	Shutdown().AddShutdownAction("config_watcher", stop)
+}
+
+func equalLevel(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 type Config struct {
 	NodeId_     string       `yaml:"NodeId"`
 	Hack        utility.Hack `yaml:"Hack"`
@@ -39,9 +107,19 @@ type Config struct {
 	Http     Http     `yaml:"Http"`
 	MySql    MySql    `yaml:"MySql"`
 	Redis    Redis    `yaml:"Redis"`
+	Auth     Auth     `yaml:"Auth"`
 
 	O11Y   utility.O11YConfig `yaml:"O11Y"`
 	Logger wlog.Config        `yaml:"Logger"`
+
+	path string // the source of config file
+}
+
+func (c *Config) AdminGuard() utility.AdminGuard {
+	return utility.AdminGuard{
+		Hack:  c.Hack,
+		Token: c.O11Y.AdminToken,
+	}
 }
 
 func (c *Config) NodeId() string {
@@ -66,13 +144,20 @@ func (c *Config) NodeId() string {
 type Filepath struct {
 	Logger string `yaml:"Logger"` // output to stderr if empty
 	Event  string `yaml:"Event"`  // output to stdout if empty
+	JwtKey string `yaml:"JwtKey"` // ed25519 private key, see utility.LoadEd25519PrivateKey
 }
 
 type Http struct {
 	Port  string `yaml:"Port"`
 	Debug bool   `yaml:"Debug"`
+
+	// DrainDelay is the duration between the readiness probe fails and the http server stops,
+	// it should be longer than the period of readiness probe, see utility.Shutdown.SetDrainDelay
+	DrainDelay time.Duration `yaml:"DrainDelay"`
 }
 
+// MySql Host and Port are the primary,
+// the read-only queries are routed to Replicas if they are declared, see utility.NewDBResolver
 type MySql struct {
 	User     string `yaml:"User"`
 	Password string `yaml:"Password"`
@@ -80,16 +165,84 @@ type MySql struct {
 	Port     string `yaml:"Port"`
 	Database string `yaml:"Database"`
 	Debug    bool   `yaml:"Debug"`
+
+	// TxMaxAttempts 遇到 deadlock 或 lock wait timeout 時, 整個 transaction 最多執行的次數, 0 or 1 表示不重試
+	TxMaxAttempts int           `yaml:"TxMaxAttempts"`
+	TxBackoff     time.Duration `yaml:"TxBackoff"`
+
+	// connection pool, 0 indicates the default of database/sql
+	MaxOpenConns    int           `yaml:"MaxOpenConns"`
+	MaxIdleConns    int           `yaml:"MaxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"ConnMaxLifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"ConnMaxIdleTime"`
+
+	// timeout, 0 indicates no timeout
+	DialTimeout   time.Duration `yaml:"DialTimeout"`
+	ReadTimeout   time.Duration `yaml:"ReadTimeout"`
+	WriteTimeout  time.Duration `yaml:"WriteTimeout"`
+	QueryTimeout  time.Duration `yaml:"QueryTimeout"`  // each statement, see utility.NewGormO11YPlugin
+	SlowThreshold time.Duration `yaml:"SlowThreshold"` // the slower statements are logged by warn level
+
+	// Optional Field
+	Replicas      []MySqlReplica        `yaml:"Replicas"`      // the same User, Password, Database as primary
+	ReplicaPolicy utility.ReplicaPolicy `yaml:"ReplicaPolicy"` // round_robin or least_latency, default is round_robin
+	ProbeInterval time.Duration         `yaml:"ProbeInterval"` // the replica is evicted when probe fails, default is 5s
+}
+
+func (conf *MySql) SetHost(host string) {
+	conf.Host = host
+}
+
+func (conf *MySql) SetPort(port string) {
+	conf.Port = port
+}
+
+type MySqlReplica struct {
+	Host string `yaml:"Host"`
+	Port string `yaml:"Port"`
 }
 
 func (conf *MySql) DSN() string {
-	return fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local",
+	return conf.dsn(conf.Host, conf.Port)
+}
+
+func (conf *MySql) ReplicaDSN(replica MySqlReplica) string {
+	return conf.dsn(replica.Host, replica.Port)
+}
+
+func (conf *MySql) dsn(host string, port string) string {
+	dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local",
 		conf.User,
 		conf.Password,
-		conf.Host,
-		conf.Port,
+		host,
+		port,
 		conf.Database,
 	)
+	if conf.DialTimeout > 0 {
+		dsn += "&timeout=" + conf.DialTimeout.String()
+	}
+	if conf.ReadTimeout > 0 {
+		dsn += "&readTimeout=" + conf.ReadTimeout.String()
+	}
+	if conf.WriteTimeout > 0 {
+		dsn += "&writeTimeout=" + conf.WriteTimeout.String()
+	}
+	return dsn
+}
+
+func (conf *MySql) SetConnPool(db *sql.DB) {
+	db.SetMaxOpenConns(conf.MaxOpenConns)
+	if conf.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(conf.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+}
+
+type Auth struct {
+	Issuer          string        `yaml:"Issuer"`
+	AccessTokenTTL  time.Duration `yaml:"AccessTokenTTL"`
+	RefreshTokenTTL time.Duration `yaml:"RefreshTokenTTL"`
 }
 
 type Redis struct {
@@ -4,38 +4,95 @@ import (
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc/codes"
+
+	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 var (
 	ErrInvalidParam = ErrorRegistry().
 		AddErrorCode(4000).
 		AddHttpStatus(http.StatusBadRequest).
+		AddTranslation("zh-TW", "參數錯誤").
 		NewError("invalid parameter")
 	ErrExists = ErrorRegistry().
 		AddErrorCode(4001).
 		AddHttpStatus(http.StatusConflict).
+		AddTranslation("zh-TW", "資源已存在").
 		NewError("resource already existed")
 	ErrNotExists = ErrorRegistry().
 		AddErrorCode(4002).
 		AddHttpStatus(http.StatusNotFound).
+		AddTranslation("zh-TW", "資源不存在").
 		NewError("resource does not exist")
 	ErrInvalidHttpMethod = ErrorRegistry().
 		AddErrorCode(4003).
 		AddHttpStatus(http.StatusMethodNotAllowed).
+		AddTranslation("zh-TW", "不支援的 http method").
 		WrapError("invalid http method", fiber.ErrMethodNotAllowed)
+	ErrVersionConflict = ErrorRegistry().
+		AddErrorCode(4004).
+		AddHttpStatus(http.StatusConflict).
+		AddTranslation("zh-TW", "資源已被修改, 請重新操作").
+		NewError("resource has been modified, please retry")
+	ErrUnauthorized = ErrorRegistry().
+		AddErrorCode(4005).
+		AddHttpStatus(http.StatusUnauthorized).
+		AddTranslation("zh-TW", "尚未登入或憑證無效").
+		NewError("authentication is required")
+	ErrTokenExpired = ErrorRegistry().
+		AddErrorCode(4006).
+		AddTranslation("zh-TW", "憑證已過期").
+		WrapError("token is expired", ErrUnauthorized)
 
 	ErrSystem = ErrorRegistry().
 		AddErrorCode(5000).
 		AddHttpStatus(http.StatusInternalServerError).
+		AddTranslation("zh-TW", "系統異常").
 		NewError("system issue")
 	ErrDatabase = ErrorRegistry().
 		AddErrorCode(5001).
 		AddHttpStatus(http.StatusInternalServerError).
+		AddCategory(utility.ErrorCategoryDependency).
+		AddGrpcCode(codes.Unavailable).
+		AddTranslation("zh-TW", "資料庫異常").
 		NewError("database issue")
+	ErrExternalService = ErrorRegistry().
+		AddErrorCode(5002).
+		AddHttpStatus(http.StatusBadGateway).
+		AddCategory(utility.ErrorCategoryDependency).
+		AddTranslation("zh-TW", "外部服務異常").
+		NewError("external service issue")
 )
 
 var (
 	ErrInvalidUsername = ErrorRegistry().
 		AddErrorCode(6000).
+		AddTranslation("zh-TW", "使用者名稱必須包含大寫字母").
 		WrapError("username must be having a upper letter", ErrInvalidParam)
+	ErrInvalidEmail = ErrorRegistry().
+		AddErrorCode(6001).
+		AddTranslation("zh-TW", "電子郵件格式錯誤").
+		WrapError("email format is invalid", ErrInvalidParam)
+	ErrInvalidPassword = ErrorRegistry().
+		AddErrorCode(6002).
+		AddTranslation("zh-TW", "密碼長度必須為 {min} 到 {max} 個字元").
+		WrapError("password must be {min} to {max} characters", ErrInvalidParam)
+	ErrIncorrectPassword = ErrorRegistry().
+		AddErrorCode(6003).
+		AddHttpStatus(http.StatusUnauthorized).
+		AddTranslation("zh-TW", "帳號或密碼錯誤").
+		NewError("username or password is incorrect")
+	ErrUserDisabled = ErrorRegistry().
+		AddErrorCode(6004).
+		AddHttpStatus(http.StatusForbidden).
+		AddTranslation("zh-TW", "使用者已停用").
+		NewError("user is disabled")
 )
+
+// ErrorCodeRanges 錯誤代碼的保留區間, 新增錯誤代碼時, 必須落在區間之內
+var ErrorCodeRanges = []utility.ErrorCodeRange{
+	{Name: "client", Min: 4000, Max: 4999, Category: utility.ErrorCategoryClient},
+	{Name: "system", Min: 5000, Max: 5999, Category: utility.ErrorCategoryServer},
+	{Name: "domain", Min: 6000, Max: 6999, Category: utility.ErrorCategoryClient},
+}
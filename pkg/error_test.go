@@ -0,0 +1,11 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+func TestErrorCatalog(t *testing.T) {
+	utility.RequireErrorCatalog(t, ErrorRegistry(), "../docs/errors.json", ErrorCodeRanges...)
+}
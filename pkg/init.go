@@ -13,6 +13,7 @@ import (
 
 func init() {
 	_Shutdown.Store(utility.NewShutdown(context.Background(), -1, Logger().Slog()))
+	_HealthRegistry = utility.NewHealthRegistry(Shutdown())
 	go Shutdown().Serve()
 }
 
@@ -25,12 +26,26 @@ func Init(conf *Config) io.Closer {
 		}
 	}()
 
-	logWriter, err := initLogger(conf.Filepath.Logger, &conf.Logger)
+	otlpHandler, err := utility.InitO11YLogger(&conf.O11Y, Shutdown(), Version().ServiceName, Logger())
+	if err != nil {
+		Logger().Slog().Error("init o11y logger fail", slog.Any("err", err))
+		return nil
+	}
+
+	logWriter, err := initLogger(conf.Filepath.Logger, &conf.Logger, otlpHandler)
 	if err != nil {
 		Logger().Slog().Error("init logger fail", slog.Any("err", err))
 		return nil
 	}
 
+	err = initEventLogger(conf.Filepath.Event)
+	if err != nil {
+		Logger().Slog().Error("init event logger fail", slog.Any("err", err))
+		return nil
+	}
+
+	Shutdown().SetDrainDelay(conf.Http.DrainDelay)
+
 	Logger().Slog().Debug("show config",
 		slog.Any("conf", wlog.JsonValue(true, conf)),
 		slog.String("node_id", conf.NodeId()),
@@ -50,8 +65,8 @@ func Init(conf *Config) io.Closer {
 
 //
 
-func initLogger(filename string, conf *wlog.Config) (w io.WriteCloser, err error) {
-	wlogger, w, err := wlog.LoggerFactory(filename, conf)
+func initLogger(filename string, conf *wlog.Config, extra ...slog.Handler) (w io.WriteCloser, err error) {
+	wlogger, w, err := wlog.LoggerFactory(filename, conf, extra...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +88,28 @@ func Logger() *wlog.Logger {
 
 //
 
+func initEventLogger(filename string) error {
+	logger, w, err := wlog.EventLoggerFactory(filename)
+	if err != nil {
+		return err
+	}
+
+	if filename != "" {
+		Shutdown().AddPriorityShutdownAction(utility.TelemetryPriority, "event_log", w.Close)
+	}
+	EventLogger().PointToNew(logger)
+	return nil
+}
+
+var _EventLogger = wlog.NewEventLogger(os.Stdout)
+
+// EventLogger records domain events and security actions (audit log)
+func EventLogger() *wlog.EventLogger {
+	return _EventLogger
+}
+
+//
+
 var _Shutdown atomic.Pointer[utility.Shutdown]
 
 func Shutdown() *utility.Shutdown {
@@ -81,7 +118,16 @@ func Shutdown() *utility.Shutdown {
 
 //
 
-var _ErrorRegistry = utility.NewErrorRegistry()
+var _HealthRegistry *utility.HealthRegistry
+
+// HealthRegistry is served by /healthz and /readyz on the O11Y port
+func HealthRegistry() *utility.HealthRegistry {
+	return _HealthRegistry
+}
+
+//
+
+var _ErrorRegistry = utility.NewErrorRegistry(ErrorCodeRanges...)
 
 func ErrorRegistry() *utility.ErrorRegistry {
 	return _ErrorRegistry
@@ -1,32 +1,38 @@
 package inject
 
 import (
-	"fmt"
+	"context"
 	"log/slog"
+	"net"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"gorm.io/gorm"
 
 	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/adapters"
+	"github.com/KScaesar/go-layout/pkg/adapters/api"
+	"github.com/KScaesar/go-layout/pkg/utility"
 	"github.com/KScaesar/go-layout/pkg/utility/wfiber"
 )
 
 // NewFiberRouter
-// 由於 fiber 本身的限制, error handler 必須分為兩個部分來處理
+// 由於 fiber 本身的限制, error handler 分為兩個部分來處理
 //
 // 1. fiber 本身的錯誤:
 // 比如 api route 找不到, 依靠 config.ErrorHandler 進行處理
 //
 // 2. 商業邏輯 service layer 錯誤:
-// 每個 api handler 都必須手動呼叫 adapters.HandleErrorByFiber
+// api handler 只需要 return error, 由 wfiber.Routes 集中呼叫 adapters.HandleErrorByFiber
 // 商業邏輯錯誤無法依靠 config.ErrorHandler 進行集中處理的原因, 有兩個因素互相影響:
 //
 //	2-1. 所有 mw 執行後 config.ErrorHandler 才會執行. 但 mw 在 handler 之後, 必須取得 http code
 //	2-2. middleware 無法取得 handler route, ref: https://github.com/gofiber/fiber/issues/3138
-func NewFiberRouter(conf *pkg.Config, db *gorm.DB, svc *Service) *fiber.App {
+//
+// 所以 metric, logger 與 error 轉換, 透過 wfiber.NewRoutes 註冊在每個 route 上
+//
+// tx 不在 router 建立, 由 app 層的 EasyTransaction 負責, 才能在 deadlock 時重新執行整個 use case
+func NewFiberRouter(conf *pkg.Config, svc *Service) *fiber.App {
 	router := fiber.New(fiber.Config{
 		ErrorHandler:          adapters.HandleErrorByFiber,
 		AppName:               pkg.Version().ServiceName,
@@ -35,7 +41,6 @@ func NewFiberRouter(conf *pkg.Config, db *gorm.DB, svc *Service) *fiber.App {
 
 	o11yMetric := adapters.FiberO11YMetric
 	o11yLogger1, o11yLogger2 := wfiber.O11YLogger(conf.Http.Debug, conf.O11Y.EnableTrace, pkg.Logger())
-	transaction := wfiber.GormTX(db, nil, pkg.Logger())
 	router.Use(
 		recover.New(recover.Config{EnableStackTrace: true}),
 		cors.New(),
@@ -43,29 +48,62 @@ func NewFiberRouter(conf *pkg.Config, db *gorm.DB, svc *Service) *fiber.App {
 		o11yLogger1,
 	)
 
-	fixFiberIssue3138 := func(handler fiber.Handler) []fiber.Handler {
-		return []fiber.Handler{o11yMetric.Middleware, o11yLogger2, transaction, handler}
-	}
+	routes := wfiber.NewRoutes(router, adapters.HandleErrorByFiber, o11yMetric.Middleware, o11yLogger2)
+
+	routes.
+		Get("/logger/level", wfiber.ChangeLoggerLevel(conf.Hack, pkg.Logger(), pkg.EventLogger())).
+		Post("/logger/level", wfiber.ChangeLoggerLevel(conf.Hack, pkg.Logger(), pkg.EventLogger())).
+		Post("/logger/config", wfiber.ReloadLogger(conf.Hack, pkg.Logger(), pkg.EventLogger()))
+
+	v1 := routes.Group("/api/v1")
+
+	v1.
+		Post("/users", api.RegisterUserFiber(svc.UserService)).
+		Post("/auth/login", api.LoginUserFiber(svc.UserService)).
+		Post("/auth/refresh", api.RefreshTokenFiber(svc.UserService))
 
-	router.Get("/logger/level", fixFiberIssue3138(wfiber.ChangeLoggerLevel(conf.Hack, pkg.Logger()))...)
-	router.Post("/logger/level", fixFiberIssue3138(wfiber.ChangeLoggerLevel(conf.Hack, pkg.Logger()))...)
+	v1.Group("", wfiber.Authenticate(svc.Auth.VerifyAccessToken, pkg.ErrUnauthorized)).
+		Post("/auth/logout", api.LogoutUserFiber(svc.UserService)).
+		Get("/users", api.QueryMultiUserFiber(svc.UserService)).
+		Put("/users/me/password", api.UpdateUserPasswordFiber(svc.UserService))
 
 	return router
 }
 
-func ServeFiber(port string, debug bool, router *fiber.App) {
-	router.Hooks().OnListen(func(_ fiber.ListenData) error {
-		if debug {
-			wfiber.ShowRoutes(router)
-		}
-		pkg.Logger().Slog().Info("api start", slog.String("url", "http://0.0.0.0:"+port))
-		return nil
-	})
-	id := fmt.Sprintf("fiber(%p)", router)
-	pkg.Shutdown().AddPriorityShutdownAction(0, id, router.Server().Shutdown)
+func FiberServerHook(port string, debug bool, getRouter func() *fiber.App) utility.LifecycleHook {
+	var router *fiber.App
+	return utility.LifecycleHook{
+		Name:     "fiber",
+		Priority: 0,
+		Start: func(ctx context.Context) error {
+			router = getRouter()
+			router.Hooks().OnListen(func(_ fiber.ListenData) error {
+				if debug {
+					wfiber.ShowRoutes(router)
+				}
+				pkg.Logger().Slog().Info("api start", slog.String("url", "http://0.0.0.0:"+port))
+				return nil
+			})
+
+			listener, err := net.Listen("tcp", "0.0.0.0:"+port)
+			if err != nil {
+				return err
+			}
+			go func() {
+				err := router.Listener(listener)
+				if err != nil {
+					pkg.Shutdown().Notify(err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return router.ShutdownWithContext(ctx)
+		},
+	}
+}
 
-	go func() {
-		err := router.Listen("0.0.0.0:" + port)
-		pkg.Shutdown().Notify(err)
-	}()
+// ServeFiber starts the server without Lifecycle, the server is stopped by pkg.Shutdown.
+func ServeFiber(port string, debug bool, router *fiber.App) {
+	serveHook(FiberServerHook(port, debug, func() *fiber.App { return router }))
 }
@@ -2,12 +2,12 @@ package inject
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
 	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/adapters/api"
@@ -15,14 +15,20 @@ import (
 	"github.com/KScaesar/go-layout/pkg/utility/wgin"
 )
 
-func NewGinRouter(conf *pkg.Config, db *gorm.DB, svc *Service) *gin.Engine {
+// NewGinRouter
+//
+// tx 不在 router 建立, 由 app 層的 EasyTransaction 負責, 才能在 deadlock 時重新執行整個 use case
+func NewGinRouter(conf *pkg.Config, svc *Service) *gin.Engine {
 	if !conf.Http.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	router := gin.New()
+	router.HandleMethodNotAllowed = true
 	router.NoRoute(func(c *gin.Context) {
-		err := pkg.ErrNotExists.(*utility.CustomError)
-		c.JSON(err.HttpStatus(), gin.H{"msg": err.Error()})
+		c.Error(pkg.ErrNotExists)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		c.Error(pkg.ErrInvalidHttpMethod)
 	})
 
 	o11yLogger1, o11yLogger2 := wgin.O11YLogger(conf.Http.Debug, conf.O11Y.EnableTrace, pkg.Logger())
@@ -32,33 +38,56 @@ func NewGinRouter(conf *pkg.Config, db *gorm.DB, svc *Service) *gin.Engine {
 		wgin.O11YMetric(pkg.Version().ServiceName),
 		o11yLogger1,
 		o11yLogger2,
-		wgin.GormTX(db, nil, pkg.Logger()),
+		wgin.HandleError(pkg.Logger(), pkg.ErrSystem),
 	)
 
 	router.GET("/:id", api.HelloGin(conf.Hack))
-	router.GET("/logger/level", wgin.ChangeLoggerLevel(conf.Hack, pkg.Logger()))
+	router.GET("/logger/level", wgin.ChangeLoggerLevel(conf.Hack, pkg.Logger(), pkg.EventLogger()))
+	router.POST("/logger/config", wgin.ReloadLogger(conf.Hack, pkg.Logger(), pkg.EventLogger()))
 
 	v1 := router.Group("/api/v1")
 
 	v1.POST("/users", api.RegisterUser(svc.UserService))
-	v1.GET("/users", api.QueryMultiUser(svc.UserService))
+	v1.POST("/auth/login", api.LoginUser(svc.UserService))
+	v1.POST("/auth/refresh", api.RefreshToken(svc.UserService))
+
+	auth := v1.Group("", wgin.Authenticate(svc.Auth.VerifyAccessToken, pkg.ErrUnauthorized))
+
+	auth.POST("/auth/logout", api.LogoutUser(svc.UserService))
+	auth.GET("/users", api.QueryMultiUser(svc.UserService))
+	auth.PUT("/users/me/password", api.UpdateUserPassword(svc.UserService))
 
 	return router
 }
 
-func ServeGin(port string, handler http.Handler) {
+func GinServerHook(port string, getHandler func() http.Handler) utility.LifecycleHook {
 	server := &http.Server{
-		Addr:    "0.0.0.0:" + port,
-		Handler: handler,
+		Addr: "0.0.0.0:" + port,
 	}
-	id := fmt.Sprintf("gin(%p)", server)
-	pkg.Shutdown().AddPriorityShutdownAction(0, id, func() error {
-		return server.Shutdown(context.Background())
-	})
+	return utility.LifecycleHook{
+		Name:     "gin",
+		Priority: 0,
+		Start: func(ctx context.Context) error {
+			server.Handler = getHandler()
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
 
-	go func() {
-		pkg.Logger().Slog().Info("api start", slog.String("url", "http://0.0.0.0:"+port))
-		err := server.ListenAndServe()
-		pkg.Shutdown().Notify(err)
-	}()
+			pkg.Logger().Slog().Info("api start", slog.String("url", "http://0.0.0.0:"+port))
+			go func() {
+				err := server.Serve(listener)
+				if !errors.Is(err, http.ErrServerClosed) {
+					pkg.Shutdown().Notify(err)
+				}
+			}()
+			return nil
+		},
+		Stop: server.Shutdown,
+	}
+}
+
+// ServeGin starts the server without Lifecycle, the server is stopped by pkg.Shutdown.
+func ServeGin(port string, handler http.Handler) {
+	serveHook(GinServerHook(port, func() http.Handler { return handler }))
 }
@@ -0,0 +1,72 @@
+package inject
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+// NewLifecycle declares the startup order of the application,
+// the stop hooks are handed to shutdown after each component is started.
+//
+// The infra hook owns the cleanup of NewInfra,
+// it is called by shutdown or by Lifecycle when a later hook fails and rolls back.
+func NewLifecycle(conf *pkg.Config, shutdown *utility.Shutdown) *utility.Lifecycle {
+	var cleanup func()
+	var router *fiber.App
+	// var router *gin.Engine
+
+	return utility.NewLifecycle(shutdown, pkg.Logger().Slog()).
+		Append(utility.LifecycleHook{
+			Name:         "infra",
+			Priority:     2,
+			StartTimeout: 30 * time.Second,
+			Start: func(ctx context.Context) error {
+				infra, cleanupInfra, err := NewInfra(conf)
+				if err != nil {
+					return err
+				}
+				svc := NewService(conf, infra)
+				router = NewFiberRouter(conf, svc)
+				// router = NewGinRouter(conf, svc)
+				cleanup = cleanupInfra
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				if cleanup == nil {
+					return nil
+				}
+				// sql.DB.Close waits for the running queries
+				return utility.StopWithCtx(ctx, func() error {
+					cleanup()
+					return nil
+				})
+			},
+		}).
+		Append(utility.O11YServerHook(
+			conf.O11Y.Port,
+			shutdown,
+			pkg.HealthRegistry(),
+			pkg.ErrorRegistry(),
+			conf.AdminGuard(),
+			pkg.EventLogger(),
+			pkg.Logger().Slog(),
+		)).
+		Append(FiberServerHook(conf.Http.Port, conf.Http.Debug, func() *fiber.App { return router }))
+	// Append(GinServerHook(conf.Http.Port, func() http.Handler { return router }))
+}
+
+// serveHook keeps the behavior of the servers started without Lifecycle,
+// the startup failure triggers pkg.Shutdown.
+func serveHook(hook utility.LifecycleHook) {
+	err := utility.NewLifecycle(pkg.Shutdown(), pkg.Logger().Slog()).
+		Append(hook).
+		Start(context.Background())
+	if err != nil {
+		pkg.Shutdown().Notify(err)
+	}
+}
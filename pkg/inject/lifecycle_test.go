@@ -0,0 +1,91 @@
+//go:build intg
+
+package inject
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg"
+	"github.com/KScaesar/go-layout/pkg/utility"
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+var testConfig = pkg.Config{
+	MySql: pkg.MySql{
+		User:     "root",
+		Password: "1234",
+		Database: "testdata",
+	},
+	Http: pkg.Http{
+		Debug: true, // keys/example.key is only allowed in debug
+	},
+	Filepath: pkg.Filepath{
+		JwtKey: "../../keys/example.key",
+	},
+	Auth: pkg.Auth{
+		Issuer:          "lifecycle_test",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	},
+}
+
+func TestMain(m *testing.M) {
+	pkg.Logger().PointToNew(wlog.NewDiscardLogger())
+	pkg.EventLogger().PointToNew(wlog.NewEventLogger(io.Discard))
+
+	DownDocker := utility.UpDocker(true, []utility.DockerService{
+		utility.NewMySqlService("mysql", &testConfig.MySql, nil),
+		utility.NewRedisService("redis", &testConfig.Redis, nil),
+	})
+
+	code := m.Run()
+	DownDocker()
+	os.Exit(code)
+}
+
+func TestNewLifecycle(t *testing.T) {
+	conf := testConfig
+	conf.Http.Port = freePort(t)
+	conf.O11Y.Port = freePort(t)
+
+	shutdown := utility.NewShutdown(context.Background(), 10, nil)
+	go shutdown.Serve()
+
+	lifecycle := NewLifecycle(&conf, shutdown)
+	require.NoError(t, lifecycle.Start(context.Background()))
+
+	resp, err := http.Get("http://127.0.0.1:" + conf.O11Y.Port + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://127.0.0.1:" + conf.Http.Port + "/api/v1/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	lifecycle.Stop()
+	assert.Empty(t, shutdown.Summary().Unfinished())
+
+	for _, port := range []string{conf.Http.Port, conf.O11Y.Port} {
+		_, err = net.DialTimeout("tcp", "127.0.0.1:"+port, time.Second)
+		assert.Error(t, err, port)
+	}
+}
+
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
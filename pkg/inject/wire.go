@@ -4,6 +4,8 @@
 package inject
 
 import (
+	"crypto/ed25519"
+
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
@@ -15,7 +17,7 @@ import (
 	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
-func NewInfra(conf *pkg.Config) (*Infra, error) {
+func NewInfra(conf *pkg.Config) (*Infra, func(), error) {
 	panic(wire.Build(
 		wire.FieldsOf(new(*pkg.Config),
 			"MySql",
@@ -24,14 +26,16 @@ func NewInfra(conf *pkg.Config) (*Infra, error) {
 
 		adapters.NewMySqlGorm,
 		adapters.NewRedis,
+		adapters.NewJwtKey,
 
 		wire.Struct(new(Infra), "*"),
 	))
 }
 
 type Infra struct {
-	MySql *gorm.DB
-	Redis *redis.Client
+	MySql  *gorm.DB
+	Redis  *redis.Client
+	JwtKey ed25519.PrivateKey
 }
 
 //
@@ -41,7 +45,9 @@ func NewService(conf *pkg.Config, infra *Infra) *Service {
 		wire.FieldsOf(new(*Infra),
 			"MySql",
 			"Redis",
+			"JwtKey",
 		),
+		adapters.NewGormTxOptions,
 		utility.NewGormEasyTransaction,
 		utility.NewGormTransaction,
 
@@ -50,6 +56,9 @@ func NewService(conf *pkg.Config, infra *Infra) *Service {
 		datastore.NewUserRepository,
 		wire.Bind(new(app.UserRepository), new(*datastore.UserRepository)),
 
+		adapters.NewJwtAuthenticator,
+		wire.Bind(new(app.TokenService), new(*adapters.JwtAuthenticator)),
+
 		app.NewUserUseCase,
 		wire.Bind(new(app.UserService), new(*app.UserUseCase)),
 
@@ -61,6 +70,7 @@ type Service struct {
 	utility.Transaction
 	utility.EasyTransaction
 	app.UserService
+	Auth *adapters.JwtAuthenticator
 }
 
 //go:generate wire gen
@@ -7,6 +7,7 @@
 package inject
 
 import (
+	"crypto/ed25519"
 	"github.com/KScaesar/go-layout/pkg"
 	"github.com/KScaesar/go-layout/pkg/adapters"
 	"github.com/KScaesar/go-layout/pkg/adapters/datastore"
@@ -18,37 +19,52 @@ import (
 
 // Injectors from wire.go:
 
-func NewInfra(conf *pkg.Config) (*Infra, error) {
+func NewInfra(conf *pkg.Config) (*Infra, func(), error) {
 	mySql := &conf.MySql
-	db, err := adapters.NewMySqlGorm(mySql)
+	db, cleanup, err := adapters.NewMySqlGorm(mySql)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	redis := &conf.Redis
-	client, err := adapters.NewRedis(redis)
+	client, cleanup2, err := adapters.NewRedis(redis)
 	if err != nil {
-		return nil, err
+		cleanup()
+		return nil, nil, err
+	}
+	privateKey, err := adapters.NewJwtKey(conf)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
 	}
 	infra := &Infra{
-		MySql: db,
-		Redis: client,
+		MySql:  db,
+		Redis:  client,
+		JwtKey: privateKey,
 	}
-	return infra, nil
+	return infra, func() {
+		cleanup2()
+		cleanup()
+	}, nil
 }
 
 func NewService(conf *pkg.Config, infra *Infra) *Service {
 	db := infra.MySql
-	transaction := utility.NewGormTransaction(db)
-	easyTransaction := utility.NewGormEasyTransaction(db)
+	v := adapters.NewGormTxOptions(conf)
+	transaction := utility.NewGormTransaction(db, v...)
+	easyTransaction := utility.NewGormEasyTransaction(db, v...)
 	userMySQL := datastore.NewUserMySQL(db)
 	client := infra.Redis
 	userRedis := datastore.NewUserRedis(client)
 	userRepository := datastore.NewUserRepository(userMySQL, userRedis)
-	userUseCase := app.NewUserUseCase(userRepository)
+	privateKey := infra.JwtKey
+	jwtAuthenticator := adapters.NewJwtAuthenticator(conf, privateKey, client)
+	userUseCase := app.NewUserUseCase(userRepository, jwtAuthenticator, easyTransaction)
 	service := &Service{
 		Transaction:     transaction,
 		EasyTransaction: easyTransaction,
 		UserService:     userUseCase,
+		Auth:            jwtAuthenticator,
 	}
 	return service
 }
@@ -56,12 +72,14 @@ func NewService(conf *pkg.Config, infra *Infra) *Service {
 // wire.go:
 
 type Infra struct {
-	MySql *gorm.DB
-	Redis *redis.Client
+	MySql  *gorm.DB
+	Redis  *redis.Client
+	JwtKey ed25519.PrivateKey
 }
 
 type Service struct {
 	utility.Transaction
 	utility.EasyTransaction
 	app.UserService
+	Auth *adapters.JwtAuthenticator
 }
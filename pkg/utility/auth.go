@@ -0,0 +1,37 @@
+package utility
+
+import (
+	"context"
+	"strings"
+)
+
+// Principal is the authenticated user of the request, see wgin.Authenticate and wfiber.Authenticate
+type Principal struct {
+	UserId   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type principalKey struct{}
+
+func CtxWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func CtxGetPrincipal(ctx context.Context) (principal Principal, ok bool) {
+	principal, ok = ctx.Value(principalKey{}).(Principal)
+	return
+}
+
+// TokenVerifier returns the principal of the valid access token,
+// the error should be the application error, e.g. pkg.ErrUnauthorized
+type TokenVerifier func(ctx context.Context, token string) (Principal, error)
+
+// ParseBearerToken parses the http header "Authorization: Bearer <token>"
+func ParseBearerToken(authorization string) (token string, ok bool) {
+	const prefix = "Bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", false
+	}
+	token = strings.TrimSpace(authorization[len(prefix):])
+	return token, token != ""
+}
@@ -7,6 +7,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 var ErrDecodeConf = errors.New("decode config")
@@ -22,12 +24,15 @@ var ErrDecodeConf = errors.New("decode config")
 // Parameters:
 // - decode: A function used to decode the content of the configuration file into an instance of type T.
 // - FilePath: The explicit path to the configuration file.
+//
+// The returned path is the source which is loaded successfully, it can be used by WatchLocalFile.
 func LoadLocalConfigFromMultiSource[T any](
 	decode Unmarshal,
 	FilePath string,
 	logger *slog.Logger,
 ) (
 	conf *T,
+	path string,
 	err error,
 ) {
 	const (
@@ -72,7 +77,6 @@ func LoadLocalConfigFromMultiSource[T any](
 		},
 	}
 
-	var path string
 	for source := byNormal; source < stop; source++ {
 		path, err = pathSources[source]()
 		if err != nil {
@@ -82,16 +86,16 @@ func LoadLocalConfigFromMultiSource[T any](
 		conf, err = LoadLocalFile[T](decode, path)
 		if err == nil {
 			logger.Info("load config", slog.String("path", path))
-			return conf, nil
+			return conf, path, nil
 		}
 
 		if errors.Is(err, ErrDecodeConf) {
-			return nil, err
+			return nil, "", err
 		}
 
 		logger.Warn("try load config", slog.String("path", path))
 	}
-	return nil, err
+	return nil, "", err
 }
 
 func LoadLocalFile[T any](decode Unmarshal, FilePath string) (*T, error) {
@@ -117,3 +121,50 @@ func LoadLocalFile[T any](decode Unmarshal, FilePath string) (*T, error) {
 
 	return &conf, nil
 }
+
+// WatchLocalFile polls the modification time and size of file,
+// onChange is called when the file is changed.
+//
+// Polling is used instead of fsnotify, because the config file mounted by k8s ConfigMap is a symlink,
+// which is replaced rather than written.
+func WatchLocalFile(FilePath string, interval time.Duration, onChange func()) (stop func() error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	fingerprint := func() (time.Time, int64) {
+		info, err := os.Stat(FilePath)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastModTime, lastSize := fingerprint()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			modTime, size := fingerprint()
+			if size < 0 || (modTime.Equal(lastModTime) && size == lastSize) {
+				continue
+			}
+			lastModTime, lastSize = modTime, size
+			onChange()
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		once.Do(func() { close(done) })
+		return nil
+	}
+}
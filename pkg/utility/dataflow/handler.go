@@ -3,6 +3,9 @@ package dataflow
 import (
 	"fmt"
 	"runtime/debug"
+	"time"
+
+	"github.com/KScaesar/go-layout/pkg/utility"
 )
 
 type ErrorHandleFunc func(message *Message, dep any, err error) error
@@ -62,3 +65,24 @@ func UseRecover() Middleware {
 		}
 	}
 }
+
+// UseRetry retries the handler when the error is retryable, see utility.IsRetryableError.
+// The interval is doubled after each attempt, and it stops when message.Ctx is done.
+func UseRetry(attempts int, interval time.Duration) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(message *Message, dep any) error {
+			return utility.RetryByError(message.Ctx, attempts, interval, func() error {
+				return next(message, dep)
+			})
+		}
+	}
+}
+
+// SettleStatus decides how the consumer settles the message by the error of handler.
+func SettleStatus(err error) utility.DataflowStatus {
+	if err == nil {
+		return utility.DataflowStatusAck
+	}
+	myErr, _ := utility.UnwrapCustomError(err)
+	return myErr.DataflowStatus()
+}
@@ -0,0 +1,236 @@
+package utility
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ReplicaPolicy string
+
+const (
+	ReplicaRoundRobin   ReplicaPolicy = "round_robin"
+	ReplicaLeastLatency ReplicaPolicy = "least_latency"
+)
+
+// NewDBResolver splits the read and write of database/sql,
+// it is used as the connection pool of gorm, e.g. mysql.New(mysql.Config{Conn: resolver})
+//
+// Routing rules:
+//
+//  1. Exec, Prepare and BeginTx always use the primary,
+//     so the statements inside a transaction (CtxGetGormTX) are forced to the primary.
+//  2. Query uses a healthy replica when the statement is read-only,
+//     e.g. SELECT without FOR UPDATE, otherwise it uses the primary.
+//  3. CtxWithPrimary forces the primary, e.g. read after write.
+//  4. If no replica is healthy, the primary is used.
+//
+// The replica is evicted when Probe fails, and it is restored after Probe succeeds.
+func NewDBResolver(primary *sql.DB, replicas []*sql.DB, policy ReplicaPolicy) *DBResolver {
+	if policy == "" {
+		policy = ReplicaRoundRobin
+	}
+
+	r := &DBResolver{
+		primary:  primary,
+		replicas: make([]*dbReplica, 0, len(replicas)),
+		policy:   policy,
+	}
+	for i, db := range replicas {
+		replica := &dbReplica{
+			name: fmt.Sprintf("replica-%d", i),
+			db:   db,
+		}
+		replica.healthy.Store(true)
+		r.replicas = append(r.replicas, replica)
+	}
+	return r
+}
+
+type DBResolver struct {
+	primary  *sql.DB
+	replicas []*dbReplica
+	policy   ReplicaPolicy
+	next     atomic.Uint64
+
+	stopWatch func()
+}
+
+type dbReplica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+	latency atomic.Int64 // nanosecond, exponentially weighted moving average of Probe
+}
+
+// gorm.ConnPool
+
+func (r *DBResolver) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.primary.PrepareContext(ctx, query)
+}
+
+func (r *DBResolver) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+func (r *DBResolver) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.route(ctx, query).QueryContext(ctx, query, args...)
+}
+
+func (r *DBResolver) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.route(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx implements gorm.TxBeginner
+func (r *DBResolver) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// GetDBConn implements gorm.GetDBConnector, gorm.DB.DB() returns the primary.
+func (r *DBResolver) GetDBConn() (*sql.DB, error) {
+	return r.primary, nil
+}
+
+//
+
+func (r *DBResolver) route(ctx context.Context, query string) *sql.DB {
+	if ctxIsPrimary(ctx) || !isReadOnlyQuery(query) {
+		return r.primary
+	}
+	replica, ok := r.pick()
+	if !ok {
+		return r.primary
+	}
+	return replica.db
+}
+
+func (r *DBResolver) pick() (*dbReplica, bool) {
+	size := len(r.replicas)
+	if size == 0 {
+		return nil, false
+	}
+
+	offset := int(r.next.Add(1) % uint64(size))
+	var target *dbReplica
+	for i := 0; i < size; i++ {
+		replica := r.replicas[(offset+i)%size]
+		if !replica.healthy.Load() {
+			continue
+		}
+		if r.policy == ReplicaRoundRobin {
+			return replica, true
+		}
+		if target == nil || replica.latency.Load() < target.latency.Load() {
+			target = replica
+		}
+	}
+	return target, target != nil
+}
+
+func isReadOnlyQuery(query string) bool {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	if len(query) < len("SELECT") || !strings.EqualFold(query[:len("SELECT")], "SELECT") {
+		return false
+	}
+
+	upper := strings.ToUpper(query)
+	return !strings.Contains(upper, "FOR UPDATE") &&
+		!strings.Contains(upper, "FOR SHARE") &&
+		!strings.Contains(upper, "LOCK IN SHARE MODE")
+}
+
+//
+
+type ctxKeyPrimary struct{}
+
+// CtxWithPrimary forces the queries of ctx to use the primary,
+// e.g. the replication lag is not acceptable.
+func CtxWithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyPrimary{}, true)
+}
+
+func ctxIsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(ctxKeyPrimary{}).(bool)
+	return primary
+}
+
+//
+
+// Probe pings all replicas, the failed replica is evicted until the next successful Probe.
+// The returned error only indicates the replicas are degraded, the primary is still available.
+func (r *DBResolver) Probe(ctx context.Context) error {
+	errs := make([]error, len(r.replicas))
+
+	var wg sync.WaitGroup
+	for i, replica := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = replica.probe(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (replica *dbReplica) probe(ctx context.Context) error {
+	start := time.Now()
+	err := replica.db.PingContext(ctx)
+	if err != nil {
+		replica.healthy.Store(false)
+		return fmt.Errorf("%v: %w", replica.name, err)
+	}
+
+	const weight = 0.3
+	sample := time.Since(start).Nanoseconds()
+	old := replica.latency.Load()
+	if old != 0 {
+		sample = int64(weight*float64(sample) + (1-weight)*float64(old))
+	}
+	replica.latency.Store(sample)
+	replica.healthy.Store(true)
+	return nil
+}
+
+// Watch calls Probe periodically until Close.
+func (r *DBResolver) Watch(interval time.Duration) {
+	if len(r.replicas) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stopWatch = cancel
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ctxProbe, cancel := context.WithTimeout(ctx, interval)
+				r.Probe(ctxProbe)
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops Watch and closes the primary and replicas.
+func (r *DBResolver) Close() error {
+	if r.stopWatch != nil {
+		r.stopWatch()
+	}
+
+	errs := []error{r.primary.Close()}
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package utility
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBResolver_route(t *testing.T) {
+	primary := sql.OpenDB(&recordConnector{statements: new([]string)})
+	replica1 := sql.OpenDB(&recordConnector{statements: new([]string)})
+	replica2 := sql.OpenDB(&recordConnector{statements: new([]string)})
+	resolver := NewDBResolver(primary, []*sql.DB{replica1, replica2}, ReplicaRoundRobin)
+	defer resolver.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		query string
+		want  []*sql.DB
+	}{
+		{name: "select", ctx: ctx, query: "SELECT * FROM `users`", want: []*sql.DB{replica1, replica2}},
+		{name: "lower case", ctx: ctx, query: " (select 1)", want: []*sql.DB{replica1, replica2}},
+		{name: "lock", ctx: ctx, query: "SELECT * FROM `users` WHERE id = ? FOR UPDATE", want: []*sql.DB{primary}},
+		{name: "write", ctx: ctx, query: "UPDATE `users` SET name = ?", want: []*sql.DB{primary}},
+		{name: "force primary", ctx: CtxWithPrimary(ctx), query: "SELECT 1", want: []*sql.DB{primary}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 4 {
+				assert.Contains(t, tt.want, resolver.route(tt.ctx, tt.query))
+			}
+		})
+	}
+}
+
+func TestDBResolver_Probe(t *testing.T) {
+	primary := sql.OpenDB(&recordConnector{statements: new([]string)})
+	replica := sql.OpenDB(&recordConnector{statements: new([]string)})
+	broken := sql.OpenDB(&brokenConnector{})
+	resolver := NewDBResolver(primary, []*sql.DB{broken, replica}, ReplicaLeastLatency)
+	defer resolver.Close()
+
+	const query = "SELECT 1"
+	ctx := context.Background()
+
+	err := resolver.Probe(ctx)
+	assert.ErrorIs(t, err, errBrokenConnector)
+	for range 4 {
+		assert.Same(t, replica, resolver.route(ctx, query))
+	}
+
+	replica.Close()
+	assert.Error(t, resolver.Probe(ctx))
+	assert.Same(t, primary, resolver.route(ctx, query))
+}
+
+var errBrokenConnector = errors.New("connection refused")
+
+type brokenConnector struct{}
+
+func (b *brokenConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errBrokenConnector
+}
+
+func (b *brokenConnector) Driver() driver.Driver { return nil }
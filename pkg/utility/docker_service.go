@@ -60,3 +60,43 @@ func NewRedisService(svc string, conf DockerServiceConfig, commands []string) Do
 		return svc, nil
 	}
 }
+
+// NewMySqlService waits for MySQL, and then calls migrate after host and port are set,
+// so the tests always run against the latest schema.
+func NewMySqlService(svc string, conf DockerServiceConfig, migrate func(ctx context.Context) error) DockerService {
+	return func(compose tc.ComposeStack, ctx context.Context) (string, error) {
+		container, err := compose.ServiceContainer(ctx, svc)
+		if err != nil {
+			return svc, err
+		}
+
+		waitStrategy := wait.ForAll(
+			wait.ForListeningPort("3306/tcp").WithStartupTimeout(60*time.Second),
+			wait.ForLog("port: 3306  MySQL Community Server").WithStartupTimeout(60*time.Second),
+		)
+		err = waitStrategy.WaitUntilReady(ctx, container)
+		if err != nil {
+			return svc, fmt.Errorf("wait ready: %w", err)
+		}
+
+		host, err := container.Host(ctx)
+		if err != nil {
+			return svc, err
+		}
+		port, err := container.MappedPort(ctx, "3306")
+		if err != nil {
+			return svc, err
+		}
+
+		conf.SetHost(host)
+		conf.SetPort(port.Port())
+
+		if migrate != nil {
+			err = migrate(ctx)
+			if err != nil {
+				return svc, fmt.Errorf("migrate: %w", err)
+			}
+		}
+		return svc, nil
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"slices"
 	"strings"
+
+	"google.golang.org/grpc/codes"
 )
 
 const panicTextOfErrorRegistry = `
@@ -13,28 +14,37 @@ The error registry call sequence is incorrect.
 It must start with AddErrorCode and end with NewError or WrapError
 `
 
-func NewErrorRegistry() *ErrorRegistry {
+// NewErrorRegistry creates a registry, the ranges are optional.
+// If ranges are declared, AddErrorCode panics when the code is out of all ranges.
+// It panics when the ranges overlap.
+func NewErrorRegistry(ranges ...ErrorCodeRange) *ErrorRegistry {
+	err := verifyErrorCodeRanges(ranges)
+	if err != nil {
+		panic(err)
+	}
 	return &ErrorRegistry{
 		errCodeMap:   make(map[int]*CustomError),
 		errCodeSlice: make([]int, 0),
+		ranges:       ranges,
 	}
 }
 
 type ErrorRegistry struct {
 	errCodeMap   map[int]*CustomError // code:text
 	errCodeSlice []int                // [code...]
+	ranges       []ErrorCodeRange
 
 	target *CustomError
 }
 
 func (r *ErrorRegistry) ShowErrors() {
-	slices.SortFunc(r.errCodeSlice, func(a, b int) int { return a - b })
+	catalog := r.Catalog()
 
-	texts := make([]string, 0, len(r.errCodeSlice)+2)
+	texts := make([]string, 0, len(catalog)+2)
 
 	texts = append(texts, "")
-	for _, errCode := range r.errCodeSlice {
-		text := fmt.Sprintf(" [ErrCode] %-8v %s", errCode, r.errCodeMap[errCode].Error())
+	for _, doc := range catalog {
+		text := fmt.Sprintf(" [ErrCode] %-8v %s", doc.Code, doc.Message)
 		texts = append(texts, text)
 	}
 	texts = append(texts, "")
@@ -50,6 +60,7 @@ func (r *ErrorRegistry) ShowErrors() {
 //	ErrInvalidParam = ErrorRegistry.
 //		AddErrorCode(4000).
 //		AddHttpStatus(http.StatusBadRequest).
+//		AddTranslation("zh-TW", "參數錯誤").
 //		NewError("invalid parameter")
 //
 //	ErrInvalidUsername = ErrorRegistry.
@@ -65,6 +76,13 @@ func (r *ErrorRegistry) AddErrorCode(errCode int) *ErrorRegistry {
 		panic("duplicated error code")
 	}
 
+	if len(r.ranges) > 0 {
+		_, ok := r.findRange(errCode)
+		if !ok {
+			panic(fmt.Sprintf("error code %v is out of all ranges", errCode))
+		}
+	}
+
 	err := &CustomError{errCode: errCode}
 	r.errCodeMap[errCode] = err
 	r.errCodeSlice = append(r.errCodeSlice, errCode)
@@ -78,6 +96,8 @@ func (r *ErrorRegistry) NewError(description string) error {
 		panic(panicTextOfErrorRegistry)
 	}
 	r.target.cause = errors.New(description)
+	r.target.description = description
+	r.applyRangeCategory()
 
 	err := r.target
 	r.target = nil
@@ -90,7 +110,9 @@ func (r *ErrorRegistry) WrapError(description string, baseError error) error {
 		panic(panicTextOfErrorRegistry)
 	}
 	r.target.cause = fmt.Errorf("%v: %w", description, baseError)
+	r.target.description = description
 	r.target.copyFrom(baseError)
+	r.applyRangeCategory()
 
 	err := r.target
 	r.target = nil
@@ -111,6 +133,7 @@ var ErrUnknown = &CustomError{
 	cause:      errors.New("unknown error"),
 	errCode:    -1,
 	httpStatus: http.StatusInternalServerError,
+	category:   ErrorCategoryServer,
 }
 
 func UnwrapCustomError(err error) (myErr *CustomError, ok bool) {
@@ -121,11 +144,19 @@ func UnwrapCustomError(err error) (myErr *CustomError, ok bool) {
 }
 
 type CustomError struct {
-	cause   error
-	errCode int
+	cause       error
+	errCode     int
+	description string
 
 	// Optional Field
-	httpStatus int
+	httpStatus   int
+	parent       *CustomError      // the base error of WrapError
+	translations map[string]string // locale:template
+
+	category       ErrorCategory
+	retryable      *bool
+	grpcCode       *codes.Code
+	dataflowStatus DataflowStatus
 }
 
 func (c *CustomError) Error() string {
@@ -143,12 +174,38 @@ func (c *CustomError) Unwrap() error {
 
 // 將 baseErr 的 Optional Field 進行複製, 想模擬繼承的概念
 func (c *CustomError) copyFrom(baseErr error) {
-	Err, _ := UnwrapCustomError(baseErr)
+	Err, ok := UnwrapCustomError(baseErr)
 	if c.httpStatus == 0 {
 		c.httpStatus = Err.httpStatus
 	}
+	if !ok {
+		return
+	}
+
+	c.parent = Err
+	if c.category == "" {
+		c.category = Err.category
+	}
+	if c.retryable == nil {
+		c.retryable = Err.retryable
+	}
+	if c.grpcCode == nil {
+		c.grpcCode = Err.grpcCode
+	}
+	if c.dataflowStatus == "" {
+		c.dataflowStatus = Err.dataflowStatus
+	}
 }
 
 func (c *CustomError) HttpStatus() int {
 	return c.httpStatus
 }
+
+// ParentErrorCode is the error code of base error which is wrapped by WrapError,
+// 0 indicates the error has no parent.
+func (c *CustomError) ParentErrorCode() int {
+	if c.parent == nil {
+		return 0
+	}
+	return c.parent.errCode
+}
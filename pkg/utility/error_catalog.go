@@ -0,0 +1,218 @@
+package utility
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// ErrorDoc describes a CustomError for frontend and partner teams.
+type ErrorDoc struct {
+	Code        int    `json:"code"`
+	HttpStatus  int    `json:"http_status"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+	ParentCode  int    `json:"parent_code,omitempty"`
+
+	Category       ErrorCategory  `json:"category"`
+	Retryable      bool           `json:"retryable"`
+	GrpcCode       string         `json:"grpc_code"`
+	DataflowStatus DataflowStatus `json:"dataflow_status"`
+
+	// Translations are the message templates of each locale
+	Translations map[string]string `json:"translations,omitempty"`
+}
+
+// Catalog returns all registered errors sorted by code.
+func (r *ErrorRegistry) Catalog() []ErrorDoc {
+	codes := slices.Clone(r.errCodeSlice)
+	slices.Sort(codes)
+
+	catalog := make([]ErrorDoc, 0, len(codes))
+	for _, code := range codes {
+		err := r.errCodeMap[code]
+		catalog = append(catalog, ErrorDoc{
+			Code:        err.errCode,
+			HttpStatus:  err.httpStatus,
+			Message:     err.Error(),
+			Description: err.description,
+			ParentCode:  err.ParentErrorCode(),
+
+			Category:       err.Category(),
+			Retryable:      err.Retryable(),
+			GrpcCode:       err.GrpcCode().String(),
+			DataflowStatus: err.DataflowStatus(),
+
+			Translations: err.translations,
+		})
+	}
+	return catalog
+}
+
+const (
+	ErrorCatalogJson    = "json"
+	ErrorCatalogOpenAPI = "openapi"
+)
+
+// MarshalCatalog exports the catalog by format, json or openapi.
+//
+// The openapi format only contains components,
+// it is merged into the api document, e.g. `$ref: '#/components/responses/Error4000'`
+func (r *ErrorRegistry) MarshalCatalog(format string) ([]byte, error) {
+	switch format {
+	case ErrorCatalogJson, "":
+		return json.MarshalIndent(r.Catalog(), "", "  ")
+	case ErrorCatalogOpenAPI:
+		return json.MarshalIndent(r.openAPIComponents(), "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported error catalog format %q", format)
+	}
+}
+
+// openAPIComponents follows the body of adapters.HandleErrorByFiber, see ErrorResponse
+//
+//	{"error":{"code":4000,"message":"invalid parameter","details":[{"field":"email","message":"invalid format"}]}}
+func (r *ErrorRegistry) openAPIComponents() map[string]any {
+	const schemaName = "ErrorResponse"
+
+	responses := make(map[string]any, len(r.errCodeSlice))
+	for _, doc := range r.Catalog() {
+		description := fmt.Sprintf("[%v] %v", doc.Code, doc.Message)
+		if doc.ParentCode != 0 {
+			description += fmt.Sprintf(" (parent: Error%v)", doc.ParentCode)
+		}
+
+		responses["Error"+strconv.Itoa(doc.Code)] = map[string]any{
+			"description":   description,
+			"x-http-status": doc.HttpStatus,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": map[string]any{"$ref": "#/components/schemas/" + schemaName},
+					"example": map[string]any{
+						"error": map[string]any{"code": doc.Code, "message": doc.Message},
+					},
+				},
+			},
+		}
+	}
+
+	return map[string]any{
+		"components": map[string]any{
+			"schemas": map[string]any{
+				schemaName: map[string]any{
+					"type":     "object",
+					"required": []string{"error"},
+					"properties": map[string]any{
+						"error": map[string]any{
+							"type":     "object",
+							"required": []string{"code", "message"},
+							"properties": map[string]any{
+								"code":    map[string]any{"type": "integer"},
+								"message": map[string]any{"type": "string"},
+								"details": map[string]any{
+									"type": "array",
+									"items": map[string]any{
+										"type":     "object",
+										"required": []string{"message"},
+										"properties": map[string]any{
+											"field":   map[string]any{"type": "string"},
+											"message": map[string]any{"type": "string"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"responses": responses,
+		},
+	}
+}
+
+// CatalogHandler serves the catalog, the format is decided by query `format`.
+func (r *ErrorRegistry) CatalogHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bData, err := r.MarshalCatalog(req.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(bData)
+	})
+}
+
+//
+
+// VerifyErrorCatalog reports
+//   - the registered error which is not in documented catalog, or is different from the document.
+//   - the documented error which is not registered.
+//   - the ranges which overlap, and the code which is not in any range.
+func VerifyErrorCatalog(registry *ErrorRegistry, documented []ErrorDoc, ranges ...ErrorCodeRange) error {
+	var errs []error
+
+	docs := make(map[int]ErrorDoc, len(documented))
+	for _, doc := range documented {
+		docs[doc.Code] = doc
+	}
+
+	catalog := registry.Catalog()
+	for _, actual := range catalog {
+		doc, ok := docs[actual.Code]
+		if !ok {
+			errs = append(errs, fmt.Errorf("code=%v: undocumented error %q", actual.Code, actual.Message))
+			continue
+		}
+		if !reflect.DeepEqual(doc, actual) {
+			errs = append(errs, fmt.Errorf("code=%v: document is outdated: documented=%+v actual=%+v", actual.Code, doc, actual))
+		}
+		delete(docs, actual.Code)
+	}
+	for code := range docs {
+		errs = append(errs, fmt.Errorf("code=%v: documented error is not registered", code))
+	}
+
+	errs = append(errs, verifyErrorCodeRanges(ranges))
+	if len(ranges) > 0 {
+		for _, actual := range catalog {
+			inRange := slices.ContainsFunc(ranges, func(r ErrorCodeRange) bool { return r.Contains(actual.Code) })
+			if !inRange {
+				errs = append(errs, fmt.Errorf("code=%v: out of all ranges", actual.Code))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RequireErrorCatalog is a test helper which compares the registry with the documented json file.
+//
+// The document is generated by `go run ./cmd -errors=json > docs/errors.json`
+func RequireErrorCatalog(t interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}, registry *ErrorRegistry, docFile string, ranges ...ErrorCodeRange) {
+	t.Helper()
+
+	bData, err := os.ReadFile(docFile)
+	if err != nil {
+		t.Fatalf("read error catalog: %v", err)
+	}
+
+	var documented []ErrorDoc
+	err = json.Unmarshal(bData, &documented)
+	if err != nil {
+		t.Fatalf("unmarshal error catalog: %v", err)
+	}
+
+	err = VerifyErrorCatalog(registry, documented, ranges...)
+	if err != nil {
+		t.Fatalf("verify error catalog:\n%v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package utility
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyErrorCatalog(t *testing.T) {
+	registry := NewErrorRegistry()
+	errInvalidParam := registry.AddErrorCode(4000).AddHttpStatus(http.StatusBadRequest).NewError("invalid parameter")
+	registry.AddErrorCode(6000).WrapError("invalid username", errInvalidParam)
+	registry.AddErrorCode(7000).NewError("out of range")
+
+	catalog := registry.Catalog()
+	assert.Equal(t, ErrorDoc{
+		Code:        6000,
+		HttpStatus:  http.StatusBadRequest,
+		Message:     "invalid username: invalid parameter",
+		Description: "invalid username",
+		ParentCode:  4000,
+
+		Category:       ErrorCategoryClient,
+		GrpcCode:       "InvalidArgument",
+		DataflowStatus: DataflowStatusDeadLetter,
+	}, catalog[1])
+
+	err := VerifyErrorCatalog(registry, catalog[:2],
+		ErrorCodeRange{Name: "client", Min: 4000, Max: 4999},
+		ErrorCodeRange{Name: "domain", Min: 4500, Max: 6999},
+	)
+	assert.ErrorContains(t, err, "code=7000: undocumented error")
+	assert.ErrorContains(t, err, "code=7000: out of all ranges")
+	assert.ErrorContains(t, err, "range client [4000, 4999] collides with domain [4500, 6999]")
+}
+
+// TestErrorRegistry_openAPISchema keeps the schema in sync with ErrorResponse
+func TestErrorRegistry_openAPISchema(t *testing.T) {
+	registry := NewErrorRegistry()
+	registry.AddErrorCode(4000).AddHttpStatus(http.StatusBadRequest).NewError("invalid parameter")
+
+	bData, err := registry.MarshalCatalog(ErrorCatalogOpenAPI)
+	require.NoError(t, err)
+
+	type schema struct {
+		Required   []string          `json:"required"`
+		Properties map[string]schema `json:"properties"`
+		Items      *schema           `json:"items"`
+	}
+	var doc struct {
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(bData, &doc))
+
+	errorSchema := doc.Components.Schemas["ErrorResponse"].Properties["error"]
+	assertSchemaFields(t, reflect.TypeOf(ErrorResponse{}), errorSchema.Required, errorSchema.Properties)
+
+	detailSchema := errorSchema.Properties["details"].Items
+	require.NotNil(t, detailSchema)
+	assertSchemaFields(t, reflect.TypeOf(ErrorDetail{}), detailSchema.Required, detailSchema.Properties)
+}
+
+// assertSchemaFields compares the schema with json tags,
+// the field without omitempty is required.
+func assertSchemaFields[V any](t *testing.T, typ reflect.Type, required []string, properties map[string]V) {
+	t.Helper()
+
+	var wantRequired, wantProperties []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, opts, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		wantProperties = append(wantProperties, name)
+		if opts != "omitempty" {
+			wantRequired = append(wantRequired, name)
+		}
+	}
+
+	assert.ElementsMatch(t, wantRequired, required, typ.Name())
+	assert.ElementsMatch(t, wantProperties, slices.Collect(maps.Keys(properties)), typ.Name())
+}
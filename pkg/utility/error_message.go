@@ -0,0 +1,198 @@
+package utility
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ErrorResponse is the http error body of all stacks, e.g. fiber, gin
+//
+//	{"error":{"code":4000,"message":"invalid parameter","details":[{"field":"email","message":"invalid format"}]}}
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+
+	// Optional Field
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail describes a sub error for client, e.g. field-level validation error.
+type ErrorDetail struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ErrorParams fills the named parameters of message template, e.g. {field}, {min}
+type ErrorParams map[string]any
+
+// ErrorWithParams attaches the runtime values to CustomError,
+// errors.Is and UnwrapCustomError work as before.
+//
+// Example:
+//
+//	ErrTooShort = ErrorRegistry.
+//		AddErrorCode(4010).
+//		AddTranslation("zh-TW", "{field} 長度至少為 {min}").
+//		WrapError("{field} must be at least {min} characters", ErrInvalidParam)
+//
+//	return utility.ErrorWithParams(ErrTooShort, utility.ErrorParams{"field": "password", "min": 8})
+func ErrorWithParams(err error, params ErrorParams) error {
+	return &errorArgs{cause: err, params: params}
+}
+
+// ErrorWithDetails attaches the sub errors which are returned to client.
+func ErrorWithDetails(err error, details ...ErrorDetail) error {
+	return &errorArgs{cause: err, details: details}
+}
+
+type errorArgs struct {
+	cause   error
+	params  ErrorParams
+	details []ErrorDetail
+}
+
+func (e *errorArgs) Error() string {
+	return renderErrorTemplate(e.cause.Error(), e.params)
+}
+
+func (e *errorArgs) Unwrap() error {
+	return e.cause
+}
+
+// collectErrorArgs merges all errorArgs in the error tree, the outer value has higher precedence.
+// The tree is walked in the same order as errors.Is, including the errors joined by multiple %w.
+func collectErrorArgs(err error) (params ErrorParams, details []ErrorDetail) {
+	params = make(ErrorParams)
+	walkErrorTree(err, func(err error) {
+		args, ok := err.(*errorArgs)
+		if !ok {
+			return
+		}
+		for key, val := range args.params {
+			if _, exist := params[key]; !exist {
+				params[key] = val
+			}
+		}
+		details = append(details, args.details...)
+	})
+	return params, details
+}
+
+// walkErrorTree visits err and its descendants in pre-order
+func walkErrorTree(err error, visit func(err error)) {
+	for err != nil {
+		visit(err)
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, child := range x.Unwrap() {
+				walkErrorTree(child, visit)
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+func renderErrorTemplate(template string, params ErrorParams) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	pairs := make([]string, 0, 2*len(params))
+	for key, val := range params {
+		pairs = append(pairs, "{"+key+"}", fmt.Sprint(val))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+//
+
+// PublicErrorMessage returns the message for client,
+// it does not contain the internal error chain, e.g. "xxx_service: ...".
+//
+// The languages are in order of preference, see ParseAcceptLanguage.
+// The first language which the error or its parents can serve is used,
+// the description of NewError or WrapError is DefaultErrorLocale.
+// If no language matches, the description is used.
+func PublicErrorMessage(err error, languages ...string) (message string, details []ErrorDetail) {
+	myErr, _ := UnwrapCustomError(err)
+	params, details := collectErrorArgs(err)
+	return renderErrorTemplate(myErr.template(languages), params), details
+}
+
+// DefaultErrorLocale is the locale of the description of NewError and WrapError.
+const DefaultErrorLocale = "en"
+
+func (c *CustomError) template(languages []string) string {
+	for _, lang := range languages {
+		text, ok := c.translate(lang)
+		if ok {
+			return text
+		}
+	}
+	return c.defaultTemplate()
+}
+
+// translate prefers the error's own template,
+// then the translation of the parent which is wrapped by WrapError.
+func (c *CustomError) translate(lang string) (string, bool) {
+	base, _, _ := strings.Cut(lang, "-")
+	for err := c; err != nil; err = err.parent {
+		text, ok := err.translations[lang]
+		if ok {
+			return text, true
+		}
+		text, ok = err.translations[base]
+		if ok {
+			return text, true
+		}
+		if base == DefaultErrorLocale {
+			return c.defaultTemplate(), true
+		}
+	}
+	return "", false
+}
+
+func (c *CustomError) defaultTemplate() string {
+	if c.description == "" {
+		return c.Error()
+	}
+	return c.description
+}
+
+// ParseAcceptLanguage converts header Accept-Language into languages in order of preference.
+//
+//	"zh-TW,zh;q=0.9,en;q=0.8" -> ["zh-TW", "zh", "en"]
+func ParseAcceptLanguage(header string) []string {
+	if header == "" {
+		return nil
+	}
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+
+	languages := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		languages = append(languages, tag.String())
+	}
+	return languages
+}
+
+// AddTranslation registers the message template of locale,
+// the locale is BCP 47 tag, e.g. zh-TW, en
+func (r *ErrorRegistry) AddTranslation(locale string, template string) *ErrorRegistry {
+	if r.target == nil {
+		panic(panicTextOfErrorRegistry)
+	}
+	if r.target.translations == nil {
+		r.target.translations = make(map[string]string)
+	}
+	r.target.translations[locale] = template
+	return r
+}
@@ -0,0 +1,62 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicErrorMessage(t *testing.T) {
+	registry := NewErrorRegistry()
+	errInvalidParam := registry.
+		AddErrorCode(4000).
+		AddHttpStatus(http.StatusBadRequest).
+		AddTranslation("zh", "參數錯誤").
+		NewError("invalid parameter")
+	errTooShort := registry.
+		AddErrorCode(4010).
+		AddTranslation("zh-TW", "{field} 長度至少為 {min}").
+		WrapError("{field} must be at least {min} characters", errInvalidParam)
+
+	err := fmt.Errorf("xxx_service: %w", ErrorWithParams(errTooShort, ErrorParams{"field": "password", "min": 8}))
+	assert.True(t, errors.Is(err, errInvalidParam))
+	assert.Equal(t, "xxx_service: password must be at least 8 characters: invalid parameter", err.Error())
+
+	message, details := PublicErrorMessage(err)
+	assert.Equal(t, "password must be at least 8 characters", message)
+	assert.Empty(t, details)
+
+	message, _ = PublicErrorMessage(err, ParseAcceptLanguage("fr;q=0.5,zh-TW,en;q=0.8")...)
+	assert.Equal(t, "password 長度至少為 8", message)
+
+	// the description is the default locale, it is a candidate as well
+	message, _ = PublicErrorMessage(err, ParseAcceptLanguage("en-US,zh-TW;q=0.8")...)
+	assert.Equal(t, "password must be at least 8 characters", message)
+
+	// the parent translation is preferred over the description in other language
+	message, _ = PublicErrorMessage(err, ParseAcceptLanguage("zh-HK,en;q=0.5")...)
+	assert.Equal(t, "參數錯誤", message)
+
+	message, _ = PublicErrorMessage(err, ParseAcceptLanguage("fr")...)
+	assert.Equal(t, "password must be at least 8 characters", message)
+
+	err = ErrorWithDetails(errInvalidParam, ErrorDetail{Field: "email", Message: "invalid format"})
+	message, details = PublicErrorMessage(err, ParseAcceptLanguage("zh-HK")...)
+	assert.Equal(t, "參數錯誤", message)
+	assert.Equal(t, []ErrorDetail{{Field: "email", Message: "invalid format"}}, details)
+
+	// the details are collected from every branch of multiple %w
+	err = fmt.Errorf("%w: %w", errInvalidParam, ErrorWithDetails(
+		ErrorWithParams(errTooShort, ErrorParams{"field": "password", "min": 8}),
+		ErrorDetail{Field: "password", Message: "too short"},
+	))
+	message, details = PublicErrorMessage(err)
+	assert.Equal(t, "invalid parameter", message)
+	assert.Equal(t, []ErrorDetail{{Field: "password", Message: "too short"}}, details)
+
+	message, _ = PublicErrorMessage(errors.New("sql: connection refused"))
+	assert.Equal(t, "unknown error", message)
+}
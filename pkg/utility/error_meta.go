@@ -0,0 +1,218 @@
+package utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+type ErrorCategory string
+
+const (
+	ErrorCategoryClient     ErrorCategory = "client"     // caller should fix the request
+	ErrorCategoryServer     ErrorCategory = "server"     // bug or unexpected state of this service
+	ErrorCategoryDependency ErrorCategory = "dependency" // database, cache, mq or external service
+)
+
+// DataflowStatus decides how the consumer of dataflow settles the message when handler fails.
+type DataflowStatus string
+
+const (
+	DataflowStatusAck        DataflowStatus = "ack"         // drop the message
+	DataflowStatusRetry      DataflowStatus = "retry"       // redeliver the message
+	DataflowStatusDeadLetter DataflowStatus = "dead_letter" // move the message to dead letter queue
+)
+
+// ErrorCodeRange is the reserved range of error code, [Min, Max]
+//
+// The Category is the default category of the codes in range.
+type ErrorCodeRange struct {
+	Name     string
+	Min      int
+	Max      int
+	Category ErrorCategory
+}
+
+func (c ErrorCodeRange) Contains(code int) bool {
+	return c.Min <= code && code <= c.Max
+}
+
+func verifyErrorCodeRanges(ranges []ErrorCodeRange) error {
+	var errs []error
+	for i, a := range ranges {
+		if a.Min > a.Max {
+			errs = append(errs, fmt.Errorf("range %v [%v, %v] is invalid", a.Name, a.Min, a.Max))
+		}
+		for _, b := range ranges[i+1:] {
+			if a.Min <= b.Max && b.Min <= a.Max {
+				errs = append(errs, fmt.Errorf("range %v [%v, %v] collides with %v [%v, %v]", a.Name, a.Min, a.Max, b.Name, b.Min, b.Max))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ErrorRegistry) findRange(code int) (ErrorCodeRange, bool) {
+	idx := slices.IndexFunc(r.ranges, func(codeRange ErrorCodeRange) bool {
+		return codeRange.Contains(code)
+	})
+	if idx < 0 {
+		return ErrorCodeRange{}, false
+	}
+	return r.ranges[idx], true
+}
+
+// applyRangeCategory is called after the inheritance of WrapError,
+// the category of range is the default only when nothing is declared or inherited.
+func (r *ErrorRegistry) applyRangeCategory() {
+	if r.target.category != "" {
+		return
+	}
+	codeRange, ok := r.findRange(r.target.errCode)
+	if ok {
+		r.target.category = codeRange.Category
+	}
+}
+
+//
+
+func (r *ErrorRegistry) AddCategory(category ErrorCategory) *ErrorRegistry {
+	if r.target == nil {
+		panic(panicTextOfErrorRegistry)
+	}
+	r.target.category = category
+	return r
+}
+
+func (r *ErrorRegistry) AddRetryable(retryable bool) *ErrorRegistry {
+	if r.target == nil {
+		panic(panicTextOfErrorRegistry)
+	}
+	r.target.retryable = &retryable
+	return r
+}
+
+func (r *ErrorRegistry) AddGrpcCode(code codes.Code) *ErrorRegistry {
+	if r.target == nil {
+		panic(panicTextOfErrorRegistry)
+	}
+	r.target.grpcCode = &code
+	return r
+}
+
+func (r *ErrorRegistry) AddDataflowStatus(status DataflowStatus) *ErrorRegistry {
+	if r.target == nil {
+		panic(panicTextOfErrorRegistry)
+	}
+	r.target.dataflowStatus = status
+	return r
+}
+
+//
+
+// Category is derived from http status when it is not declared by AddCategory or range.
+func (c *CustomError) Category() ErrorCategory {
+	if c.category != "" {
+		return c.category
+	}
+	if 400 <= c.httpStatus && c.httpStatus < 500 {
+		return ErrorCategoryClient
+	}
+	return ErrorCategoryServer
+}
+
+// Retryable indicates the same request may succeed later,
+// by default only the dependency error is retryable.
+func (c *CustomError) Retryable() bool {
+	if c.retryable != nil {
+		return *c.retryable
+	}
+	return c.Category() == ErrorCategoryDependency
+}
+
+// GrpcCode is derived from http status when it is not declared by AddGrpcCode.
+func (c *CustomError) GrpcCode() codes.Code {
+	if c.grpcCode != nil {
+		return *c.grpcCode
+	}
+	return grpcCodeFromHttpStatus(c.httpStatus)
+}
+
+// DataflowStatus is derived from Retryable when it is not declared by AddDataflowStatus.
+func (c *CustomError) DataflowStatus() DataflowStatus {
+	if c.dataflowStatus != "" {
+		return c.dataflowStatus
+	}
+	if c.Retryable() {
+		return DataflowStatusRetry
+	}
+	return DataflowStatusDeadLetter
+}
+
+func grpcCodeFromHttpStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusInternalServerError:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+//
+
+// IsRetryableError reports whether err is retryable, the unknown error is not retryable.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	myErr, _ := UnwrapCustomError(err)
+	return myErr.Retryable()
+}
+
+// RetryByError calls fn until it succeeds, the error is not retryable or attempts are exhausted.
+// The interval is doubled after each attempt.
+func RetryByError(ctx context.Context, attempts int, interval time.Duration, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = fn()
+		if !IsRetryableError(err) || i == attempts-1 {
+			return err
+		}
+
+		timer := time.NewTimer(interval << i)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
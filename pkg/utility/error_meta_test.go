@@ -0,0 +1,80 @@
+package utility
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestErrorRegistry_metadata(t *testing.T) {
+	assert.Panics(t, func() {
+		NewErrorRegistry(
+			ErrorCodeRange{Name: "client", Min: 4000, Max: 4999},
+			ErrorCodeRange{Name: "user", Min: 4500, Max: 6999},
+		)
+	})
+
+	registry := NewErrorRegistry(
+		ErrorCodeRange{Name: "client", Min: 4000, Max: 4999, Category: ErrorCategoryClient},
+		ErrorCodeRange{Name: "system", Min: 5000, Max: 5999, Category: ErrorCategoryServer},
+		ErrorCodeRange{Name: "order", Min: 7000, Max: 7999},
+	)
+	assert.PanicsWithValue(t, "error code 6000 is out of all ranges", func() {
+		registry.AddErrorCode(6000)
+	})
+	registry.target = nil
+
+	errDatabase := registry.
+		AddErrorCode(5001).
+		AddHttpStatus(http.StatusInternalServerError).
+		AddCategory(ErrorCategoryDependency).
+		AddGrpcCode(codes.Unavailable).
+		NewError("database issue")
+	errStock := registry.
+		AddErrorCode(7000).
+		WrapError("stock is locked", errDatabase)
+	errInvalidParam := registry.
+		AddErrorCode(4000).
+		AddHttpStatus(http.StatusBadRequest).
+		NewError("invalid parameter")
+	errReplica := registry.
+		AddErrorCode(5002).
+		WrapError("replica is unavailable", errDatabase)
+
+	stock, _ := UnwrapCustomError(errStock)
+	assert.Equal(t, ErrorCategoryDependency, stock.Category())
+	assert.True(t, stock.Retryable())
+	assert.Equal(t, codes.Unavailable, stock.GrpcCode())
+	assert.Equal(t, DataflowStatusRetry, stock.DataflowStatus())
+
+	// the inherited category takes precedence over the category of range
+	replica, _ := UnwrapCustomError(errReplica)
+	assert.Equal(t, ErrorCategoryDependency, replica.Category())
+
+	invalidParam, _ := UnwrapCustomError(errInvalidParam)
+	assert.Equal(t, ErrorCategoryClient, invalidParam.Category())
+	assert.False(t, invalidParam.Retryable())
+	assert.Equal(t, codes.InvalidArgument, invalidParam.GrpcCode())
+	assert.Equal(t, DataflowStatusDeadLetter, invalidParam.DataflowStatus())
+
+	attempts := 0
+	err := RetryByError(context.Background(), 3, time.Millisecond, func() error {
+		attempts++
+		return fmt.Errorf("query: %w", errStock)
+	})
+	assert.ErrorIs(t, err, errDatabase)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = RetryByError(context.Background(), 3, time.Millisecond, func() error {
+		attempts++
+		return errInvalidParam
+	})
+	assert.ErrorIs(t, err, errInvalidParam)
+	assert.Equal(t, 1, attempts)
+}
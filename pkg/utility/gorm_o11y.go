@@ -0,0 +1,249 @@
+package utility
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+// NewGormLogger writes the gorm records by the ctx logger of wlog,
+// so the records carry req_id, trace_id and so on.
+//
+// Error: the failed statements, except gorm.ErrRecordNotFound
+// Warn: the statements slower than slowThreshold, <= 0 indicates disabled
+// Info: all statements, e.g. gorm.DB.Debug()
+//
+// The sql is recorded with placeholders, the params are never written,
+// since they may carry the password hash or personal data, see ParamsFilter.
+func NewGormLogger(wlogger *wlog.Logger, slowThreshold time.Duration) logger.Interface {
+	return &gormLogger{
+		wlogger:       wlogger,
+		slowThreshold: slowThreshold,
+		level:         logger.Warn,
+	}
+}
+
+type gormLogger struct {
+	wlogger       *wlog.Logger
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Info {
+		l.wlogger.CtxGetLogger(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Warn {
+		l.wlogger.CtxGetLogger(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= logger.Error {
+		l.wlogger.CtxGetLogger(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// ParamsFilter implements gorm.ParamsFilter, the sql of Trace keeps "?" instead of the params.
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		}
+	}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		l.wlogger.CtxGetLogger(ctx).Error("gorm statement failed", append(attrs(), slog.Any("err", err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		l.wlogger.CtxGetLogger(ctx).Warn("gorm slow statement", append(attrs(), slog.Duration("threshold", l.slowThreshold))...)
+	case l.level >= logger.Info:
+		l.wlogger.CtxGetLogger(ctx).Info("gorm statement", attrs()...)
+	}
+}
+
+//
+
+func NewGormO11YMetric(svcName string) *GormO11YMetric {
+	return &GormO11YMetric{
+		StatementSecond: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: svcName,
+			Subsystem: "db",
+			Name:      "statement_duration_seconds",
+			Help:      "Histogram of statement time for database in seconds",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}, // 1 ms ~ 5 s
+		}, []string{"operation", "table", "result"}),
+	}
+}
+
+type GormO11YMetric struct {
+	StatementSecond *prometheus.HistogramVec
+}
+
+// RegisterDBStats exports sql.DBStats, e.g. open connections, wait count,
+// the name is the label db_name of go_sql_* metrics.
+func (m *GormO11YMetric) RegisterDBStats(name string, db *sql.DB) error {
+	collector := collectors.NewDBStatsCollector(db, name)
+	err := prometheus.Register(collector)
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+	return err
+}
+
+//
+
+// NewGormO11YPlugin records metric, span and timeout for each statement by gorm callbacks.
+//
+// The span is created only when ctx carries a span, e.g. wfiber.O11YTrace,
+// so the statements of background jobs do not create orphan traces.
+//
+// The timeout is not applied to gorm.DB.Rows, since the rows are consumed after the callbacks.
+func NewGormO11YPlugin(metric *GormO11YMetric, timeout time.Duration) gorm.Plugin {
+	return &gormO11YPlugin{
+		metric:  metric,
+		timeout: timeout,
+	}
+}
+
+type gormO11YPlugin struct {
+	metric  *GormO11YMetric
+	timeout time.Duration
+}
+
+func (p *gormO11YPlugin) Name() string {
+	return "o11y"
+}
+
+func (p *gormO11YPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("o11y:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("o11y:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("o11y:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("o11y:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("o11y:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("o11y:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("o11y:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("o11y:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("o11y:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("o11y:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("o11y:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("o11y:after_raw", p.after),
+	)
+}
+
+const gormO11YKey = "o11y:statement"
+
+type gormO11YStatement struct {
+	operation string
+	start     time.Time
+	ctx       context.Context // the context before the statement, it is restored after the statement
+	span      trace.Span
+	cancel    context.CancelFunc
+}
+
+func (p *gormO11YPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		stmt := &gormO11YStatement{
+			operation: operation,
+			start:     time.Now(),
+			ctx:       ctx,
+		}
+
+		if trace.SpanContextFromContext(ctx).IsValid() {
+			ctx, stmt.span = otel.Tracer("gorm").Start(ctx, operation+" "+db.Statement.Table,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("db.system", db.Dialector.Name()),
+					attribute.String("db.operation", operation),
+					attribute.String("db.sql.table", db.Statement.Table),
+				),
+			)
+		}
+		if p.timeout > 0 && operation != "row" {
+			ctx, stmt.cancel = context.WithTimeout(ctx, p.timeout)
+		}
+		db.Statement.Context = ctx
+
+		db.InstanceSet(gormO11YKey, stmt)
+	}
+}
+
+func (p *gormO11YPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormO11YKey)
+	if !ok {
+		return
+	}
+	stmt := value.(*gormO11YStatement)
+
+	if stmt.cancel != nil {
+		stmt.cancel()
+	}
+	db.Statement.Context = stmt.ctx
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	if p.metric != nil {
+		p.metric.StatementSecond.
+			WithLabelValues(stmt.operation, db.Statement.Table, result).
+			Observe(time.Since(stmt.start).Seconds())
+	}
+
+	if stmt.span != nil {
+		stmt.span.SetAttributes(
+			attribute.String("db.statement", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		if err != nil {
+			stmt.span.RecordError(err)
+			stmt.span.SetStatus(codes.Error, err.Error())
+		}
+		stmt.span.End()
+	}
+}
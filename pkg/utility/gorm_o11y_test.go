@@ -0,0 +1,78 @@
+package utility
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+func TestGormLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	conf := &wlog.Config{}
+	conf.SetJsonFormat(true)
+	wlogger := wlog.NewLogger(conf.LevelVar, wlog.NewHandler(buf, conf))
+
+	ctx := wlog.CtxWithAttrs(context.Background(), slog.String("req_id", "abc"))
+	statement := func() (string, int64) { return "SELECT * FROM `users`", 1 }
+	decode := func() map[string]any {
+		line := map[string]any{}
+		if buf.Len() == 0 {
+			return line
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		buf.Reset()
+		return line
+	}
+
+	gormLogger := NewGormLogger(wlogger, 100*time.Millisecond)
+
+	gormLogger.Trace(ctx, time.Now(), statement, nil)
+	assert.Empty(t, decode())
+
+	gormLogger.Trace(ctx, time.Now().Add(-time.Second), statement, nil)
+	line := decode()
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "abc", line["req_id"])
+
+	gormLogger.Trace(ctx, time.Now(), statement, errors.New("connection refused"))
+	line = decode()
+	assert.Equal(t, "ERROR", line["level"])
+	assert.Equal(t, "SELECT * FROM `users`", line["sql"])
+
+	gormLogger.LogMode(logger.Info).Trace(ctx, time.Now(), statement, nil)
+	line = decode()
+	assert.Equal(t, "INFO", line["level"])
+
+	// the params are not interpolated into sql
+	db, _ := newRecordGorm(t)
+	db = db.Session(&gorm.Session{Logger: gormLogger.LogMode(logger.Info)})
+	require.NoError(t, db.WithContext(ctx).Exec("UPDATE `users` SET password = ? WHERE email = ?", "$2a$10$hash", "caesar@example.com").Error)
+	line = decode()
+	assert.Equal(t, "UPDATE `users` SET password = ? WHERE email = ?", line["sql"])
+}
+
+func TestGormO11YPlugin(t *testing.T) {
+	db, statements := newRecordGorm(t)
+	metric := NewGormO11YMetric("gorm_o11y_test")
+	require.NoError(t, db.Use(NewGormO11YPlugin(metric, time.Second)))
+
+	ctx := context.Background()
+	tx := db.WithContext(ctx).Exec("UPDATE `users` SET name = ?", "caesar")
+	require.NoError(t, tx.Error)
+	assert.Equal(t, ctx, tx.Statement.Context)
+
+	assert.Equal(t, []string{"UPDATE `users` SET name = ?"}, *statements)
+	assert.Equal(t, 1, testutil.CollectAndCount(metric.StatementSecond))
+}
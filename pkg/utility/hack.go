@@ -2,8 +2,10 @@ package utility
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,3 +43,24 @@ func (hack Hack) Value() string {
 
 	return hex.EncodeToString(hashBytes)
 }
+
+// AdminGuard protects admin endpoints,
+// the request is allowed by hack challenge or bearer token.
+type AdminGuard struct {
+	Hack  Hack
+	Token string
+}
+
+// Allow checks query `hack` and header `Authorization: Bearer <token>`,
+// the token is disabled when it is empty.
+func (guard AdminGuard) Allow(hackValue string, authorization string) bool {
+	if hackValue != "" && guard.Hack.Challenge(hackValue) {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || guard.Token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(guard.Token)) == 1
+}
@@ -0,0 +1,212 @@
+package utility
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDown     = "down"
+	HealthStatusDegraded = "degraded"
+	HealthStatusDraining = "draining"
+)
+
+// HealthChecker describes a dependency which is checked by readiness probe.
+//
+// Example:
+//
+//	health.Register(HealthChecker{
+//		Name:     "mysql",
+//		Timeout:  time.Second,
+//		Critical: true,
+//		Check:    stdDB.PingContext,
+//	})
+type HealthChecker struct {
+	Name string
+
+	// Timeout limits the duration of Check, <= 0 indicates DefaultHealthTimeout.
+	Timeout time.Duration
+
+	// Critical indicates the pod is not ready when Check fails,
+	// otherwise the failure only degrades the report.
+	Critical bool
+
+	Check func(ctx context.Context) error
+}
+
+const DefaultHealthTimeout = 2 * time.Second
+
+var ErrHealthCheckerDuplicated = errors.New("health checker duplicated")
+
+type HealthResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                  `json:"status"`
+	Uptime string                  `json:"uptime"`
+	Checks map[string]HealthResult `json:"checks,omitempty"`
+}
+
+func NewHealthRegistry(shutdown *Shutdown) *HealthRegistry {
+	return &HealthRegistry{
+		shutdown: shutdown,
+		start:    time.Now(),
+	}
+}
+
+// HealthRegistry aggregates HealthChecker for liveness and readiness probes.
+//
+// Liveness only reports whether the process is able to serve http,
+// it does not run checkers, so that a broken dependency does not cause the pod to restart.
+//
+// Readiness fails as soon as Shutdown starts draining,
+// so that the traffic is removed before the http servers are stopped, see Shutdown.SetDrainDelay
+type HealthRegistry struct {
+	mu       sync.RWMutex
+	checkers []HealthChecker
+	shutdown *Shutdown
+	start    time.Time
+}
+
+func (r *HealthRegistry) Register(checker HealthChecker) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exist := slices.ContainsFunc(r.checkers, func(c HealthChecker) bool {
+		return c.Name == checker.Name
+	})
+	if exist {
+		return fmt.Errorf("name=%v: %w", checker.Name, ErrHealthCheckerDuplicated)
+	}
+	r.checkers = append(r.checkers, checker)
+	return nil
+}
+
+func (r *HealthRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = slices.DeleteFunc(r.checkers, func(c HealthChecker) bool {
+		return c.Name == name
+	})
+}
+
+func (r *HealthRegistry) Liveness() HealthReport {
+	return HealthReport{
+		Status: HealthStatusUp,
+		Uptime: time.Since(r.start).Round(time.Second).String(),
+	}
+}
+
+// Readiness runs all checkers in parallel.
+func (r *HealthRegistry) Readiness(ctx context.Context) HealthReport {
+	report := r.Liveness()
+	if r.shutdown != nil && r.shutdown.Draining() {
+		report.Status = HealthStatusDraining
+		return report
+	}
+
+	r.mu.RLock()
+	checkers := slices.Clone(r.checkers)
+	r.mu.RUnlock()
+
+	mu := sync.Mutex{}
+	report.Checks = make(map[string]HealthResult, len(checkers))
+
+	wg := sync.WaitGroup{}
+	for _, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runHealthChecker(ctx, checker)
+			mu.Lock()
+			report.Checks[checker.Name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == HealthStatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = HealthStatusDown
+			break
+		}
+		report.Status = HealthStatusDegraded
+	}
+	return report
+}
+
+func runHealthChecker(ctx context.Context, checker HealthChecker) HealthResult {
+	timeout := checker.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errCh <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := HealthResult{
+		Status:   HealthStatusUp,
+		Critical: checker.Critical,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler serves /healthz
+func (r *HealthRegistry) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeHealthReport(w, r.Liveness())
+	})
+}
+
+// ReadinessHandler serves /readyz, the http status is 503 when the status is down or draining.
+func (r *HealthRegistry) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeHealthReport(w, r.Readiness(req.Context()))
+	})
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	code := http.StatusOK
+	if report.Status == HealthStatusDown || report.Status == HealthStatusDraining {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
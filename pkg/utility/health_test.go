@@ -0,0 +1,60 @@
+package utility
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthRegistry_Readiness(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+	health := NewHealthRegistry(shutdown)
+
+	var mysqlErr error
+	require.NoError(t, health.Register(HealthChecker{
+		Name:     "mysql",
+		Critical: true,
+		Check:    func(ctx context.Context) error { return mysqlErr },
+	}))
+	require.NoError(t, health.Register(HealthChecker{
+		Name:    "cache",
+		Timeout: 10 * time.Millisecond,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	assert.ErrorIs(t, health.Register(HealthChecker{Name: "mysql"}), ErrHealthCheckerDuplicated)
+
+	report := health.Readiness(context.Background())
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+	assert.Equal(t, HealthStatusDown, report.Checks["cache"].Status)
+
+	mysqlErr = errors.New("connection refused")
+	recorder := httptest.NewRecorder()
+	health.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"status":"down"`)
+
+	mysqlErr = nil
+	health.Unregister("cache")
+	assert.Equal(t, HealthStatusUp, health.Readiness(context.Background()).Status)
+
+	// readiness fails before the components are stopped
+	var readyWhenStop string
+	shutdown.AddPriorityShutdownAction(0, "http", func() error {
+		readyWhenStop = health.Readiness(context.Background()).Status
+		return nil
+	})
+	shutdown.Notify(nil)
+	shutdown.Serve()
+
+	assert.Equal(t, HealthStatusDraining, readyWhenStop)
+	assert.Equal(t, HealthStatusUp, health.Liveness().Status)
+}
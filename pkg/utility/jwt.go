@@ -0,0 +1,124 @@
+package utility
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrTokenInvalid = errors.New("token is invalid")
+	ErrTokenExpired = errors.New("token is expired")
+)
+
+type TokenKind string
+
+const (
+	AccessToken  TokenKind = "access"
+	RefreshToken TokenKind = "refresh"
+)
+
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	Kind     TokenKind `json:"kind"`
+	Username string    `json:"username,omitempty"`
+}
+
+func (c *TokenClaims) Principal() Principal {
+	return Principal{
+		UserId:   c.Subject,
+		Username: c.Username,
+	}
+}
+
+// LoadEd25519PrivateKey reads the PKCS #8 PEM file, e.g.
+//
+//	openssl genpkey -algorithm ed25519 -out keys/example.key
+func LoadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	bData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(bData)
+	if block == nil {
+		return nil, fmt.Errorf("key file %q: pem block not found", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse pkcs8 key: %w", err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key file %q: want ed25519, got %T", path, key)
+	}
+	return privateKey, nil
+}
+
+// NewJwtCodec signs the tokens by EdDSA,
+// the access and refresh tokens are distinguished by TokenClaims.Kind.
+func NewJwtCodec(key ed25519.PrivateKey, issuer string) *JwtCodec {
+	return &JwtCodec{
+		key:    key,
+		issuer: issuer,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+			jwt.WithIssuer(issuer),
+			jwt.WithExpirationRequired(),
+		),
+	}
+}
+
+type JwtCodec struct {
+	key    ed25519.PrivateKey
+	issuer string
+	parser *jwt.Parser
+}
+
+// Encode uses ulid as jti, which is the key of revocation list.
+func (j *JwtCodec) Encode(principal Principal, kind TokenKind, ttl time.Duration) (token string, claims TokenClaims, err error) {
+	now := time.Now()
+	claims = TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    j.issuer,
+			Subject:   principal.UserId,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        NewUlid(),
+		},
+		Kind:     kind,
+		Username: principal.Username,
+	}
+
+	token, err = jwt.NewWithClaims(jwt.SigningMethodEdDSA, &claims).SignedString(j.key)
+	if err != nil {
+		return "", TokenClaims{}, fmt.Errorf("sign jwt: %w", err)
+	}
+	return token, claims, nil
+}
+
+// Decode verifies the signature, issuer, expiration and kind,
+// empty kind indicates any kind is accepted.
+func (j *JwtCodec) Decode(token string, kind TokenKind) (claims TokenClaims, err error) {
+	_, err = j.parser.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return j.key.Public(), nil
+	})
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return TokenClaims{}, fmt.Errorf("%w: %w", ErrTokenExpired, err)
+	}
+	if err != nil {
+		return TokenClaims{}, fmt.Errorf("%w: %w", ErrTokenInvalid, err)
+	}
+
+	if kind != "" && claims.Kind != kind {
+		return TokenClaims{}, fmt.Errorf("%w: want %v token, got %v", ErrTokenInvalid, kind, claims.Kind)
+	}
+	return claims, nil
+}
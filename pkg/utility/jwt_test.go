@@ -0,0 +1,60 @@
+package utility
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJwtCodec(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	codec := NewJwtCodec(key, "jwt_test")
+	principal := Principal{UserId: "01J0000000000000000000000", Username: "Caesar"}
+
+	token, claims, err := codec.Encode(principal, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	decoded, err := codec.Decode(token, AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, principal, decoded.Principal())
+	assert.Equal(t, claims.ID, decoded.ID)
+
+	_, err = codec.Decode(token, RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	expired, _, err := codec.Encode(principal, AccessToken, -time.Minute)
+	require.NoError(t, err)
+	_, err = codec.Decode(expired, "")
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged, _, err := NewJwtCodec(otherKey, "jwt_test").Encode(principal, AccessToken, time.Minute)
+	require.NoError(t, err)
+	_, err = codec.Decode(forged, "")
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+func TestParseBearerToken(t *testing.T) {
+	tests := []struct {
+		authorization string
+		want          string
+		ok            bool
+	}{
+		{authorization: "Bearer abc", want: "abc", ok: true},
+		{authorization: "bearer abc", want: "abc", ok: true},
+		{authorization: "Bearer ", ok: false},
+		{authorization: "Basic abc", ok: false},
+		{authorization: "", ok: false},
+	}
+	for _, tt := range tests {
+		token, ok := ParseBearerToken(tt.authorization)
+		assert.Equal(t, tt.ok, ok, tt.authorization)
+		assert.Equal(t, tt.want, token, tt.authorization)
+	}
+}
@@ -0,0 +1,168 @@
+package utility
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LifecycleHook describes how a component is started and stopped.
+//
+// After Start succeeds, Stop is handed to Shutdown as ShutdownComponent,
+// Priority and DependsOn have the same meaning as Shutdown.AddPriorityShutdownComponent.
+type LifecycleHook struct {
+	Name      string
+	Priority  uint
+	DependsOn []string
+
+	// StartTimeout limits the duration of Start, <= 0 indicates no limit.
+	// When it elapses, the ctx of Start is canceled and Lifecycle waits for Start to return,
+	// so Start must respect ctx.
+	StartTimeout time.Duration
+
+	// StopTimeout limits the duration of Stop, <= 0 indicates no limit.
+	StopTimeout time.Duration
+
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// NewLifecycle creates a Lifecycle which is symmetric to Shutdown.
+//
+// Example:
+//
+//	err := utility.NewLifecycle(shutdown, logger).
+//		Append(utility.LifecycleHook{Name: "infra", Start: ...}).
+//		Append(utility.O11YServerHook(...)).
+//		Start(ctx)
+func NewLifecycle(shutdown *Shutdown, logger *slog.Logger) *Lifecycle {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Lifecycle{
+		shutdown: shutdown,
+		logger:   logger,
+	}
+}
+
+type Lifecycle struct {
+	hooks    []LifecycleHook
+	shutdown *Shutdown
+	logger   *slog.Logger
+}
+
+// Append registers a hook, the hooks are started in the order of Append.
+func (l *Lifecycle) Append(hook LifecycleHook) *Lifecycle {
+	l.hooks = append(l.hooks, hook)
+	return l
+}
+
+// Start starts all hooks one by one.
+//
+// If a hook fails, the started hooks are stopped in reverse order and removed from Shutdown,
+// so that the failure does not leak running components.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.logger.Info("startup start", slog.Int("qty", len(l.hooks)))
+	start := time.Now()
+
+	started := make([]LifecycleHook, 0, len(l.hooks))
+	for i, hook := range l.hooks {
+		err := l.startHook(ctx, i+1, hook)
+		if err == nil {
+			err = l.shutdown.AddPriorityShutdownComponent(hook.Priority, ShutdownComponent{
+				Name:      hook.Name,
+				DependsOn: hook.DependsOn,
+				Timeout:   hook.StopTimeout,
+				Stop:      hook.Stop,
+			})
+			if err != nil {
+				l.stopHook(hook)
+			}
+		}
+
+		if err != nil {
+			l.rollback(started)
+			return fmt.Errorf("start %v: %w", hook.Name, err)
+		}
+		started = append(started, hook)
+	}
+
+	duration := time.Since(start)
+	l.logger.Info("startup finish", slog.String("duration", duration.String()))
+	return nil
+}
+
+func (l *Lifecycle) startHook(ctx context.Context, seq int, hook LifecycleHook) error {
+	logger := l.logger.With(
+		slog.Int("no.", seq),
+		slog.String("component", hook.Name),
+	)
+	if hook.Start == nil {
+		return nil
+	}
+
+	if hook.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.StartTimeout)
+		defer cancel()
+	}
+
+	logger.Info("start")
+	start := time.Now()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- hook.Start(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+
+		// Start may still write the shared state, it must finish before the next hook or rollback
+		lateErr := <-result
+		if lateErr == nil {
+			l.stopHook(hook)
+		}
+	}
+	if err != nil {
+		logger.Error("start fail", slog.Any("err", err))
+		return err
+	}
+
+	duration := time.Since(start)
+	logger.Info("start finish", slog.String("duration", duration.String()))
+	return nil
+}
+
+func (l *Lifecycle) rollback(started []LifecycleHook) {
+	for i := len(started) - 1; i >= 0; i-- {
+		hook := started[i]
+		l.shutdown.RemoveShutdownComponent(hook.Name)
+		l.stopHook(hook)
+	}
+}
+
+func (l *Lifecycle) stopHook(hook LifecycleHook) {
+	if hook.Stop == nil {
+		return
+	}
+	l.shutdown.stopComponent(&component{
+		ShutdownComponent: ShutdownComponent{
+			Name:    hook.Name,
+			Timeout: hook.StopTimeout,
+			Stop:    hook.Stop,
+		},
+		priority: int(hook.Priority),
+	}, 0)
+}
+
+// Stop triggers Shutdown and waits for all components to stop,
+// it requires Shutdown.Serve to be running, e.g. `go shutdown.Serve()`.
+func (l *Lifecycle) Stop() {
+	l.shutdown.Notify(nil)
+	<-l.shutdown.WaitChannel()
+}
@@ -0,0 +1,109 @@
+package utility
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle_rollback(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+	go shutdown.Serve()
+
+	var events []string
+	hook := func(name string, priority uint, startErr error) LifecycleHook {
+		return LifecycleHook{
+			Name:     name,
+			Priority: priority,
+			Start: func(ctx context.Context) error {
+				events = append(events, "start "+name)
+				return startErr
+			},
+			Stop: func(context.Context) error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	errBind := errors.New("address already in use")
+	lifecycle := NewLifecycle(shutdown, nil).
+		Append(hook("db", 2, nil)).
+		Append(hook("metric", 1, nil)).
+		Append(hook("http", 0, errBind))
+
+	err := lifecycle.Start(context.Background())
+	require.ErrorIs(t, err, errBind)
+	assert.Equal(t, []string{"start db", "start metric", "start http", "stop metric", "stop db"}, events)
+
+	// the rollback components are not stopped again
+	events = nil
+	lifecycle.Stop()
+	assert.Empty(t, events)
+}
+
+func TestLifecycle_startTimeout(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+	go shutdown.Serve()
+
+	stopped, brokerStarted, brokerStopped := false, false, false
+	lifecycle := NewLifecycle(shutdown, nil).
+		Append(LifecycleHook{
+			Name: "db",
+			Stop: func(context.Context) error {
+				stopped = true
+				return nil
+			},
+		}).
+		Append(LifecycleHook{
+			Name:         "broker",
+			StartTimeout: 10 * time.Millisecond,
+			Start: func(ctx context.Context) error {
+				<-ctx.Done()
+				brokerStarted = true
+				return nil
+			},
+			Stop: func(context.Context) error {
+				brokerStopped = true
+				return nil
+			},
+		})
+
+	err := lifecycle.Start(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, stopped)
+
+	// Start has returned before Lifecycle.Start, the late success is stopped
+	assert.True(t, brokerStarted)
+	assert.True(t, brokerStopped)
+}
+
+func TestLifecycle_Stop(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+	go shutdown.Serve()
+
+	var events []string
+	lifecycle := NewLifecycle(shutdown, nil)
+	for _, name := range []string{"db", "http"} {
+		priority := uint(0)
+		if name == "db" {
+			priority = 2
+		}
+		lifecycle.Append(LifecycleHook{
+			Name:     name,
+			Priority: priority,
+			Stop: func(context.Context) error {
+				events = append(events, name)
+				return nil
+			},
+		})
+	}
+
+	require.NoError(t, lifecycle.Start(context.Background()))
+	lifecycle.Stop()
+	assert.Equal(t, []string{"http", "db"}, events)
+}
@@ -0,0 +1,401 @@
+package utility
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMigrationLocked   = errors.New("migration is locked by another process")
+	ErrMigrationChecksum = errors.New("migration checksum mismatch")
+	ErrMigrationMissing  = errors.New("applied migration is missing")
+)
+
+// Migration is a versioned sql file pair, e.g.
+//
+//	0001_create_users.up.sql
+//	0001_create_users.down.sql
+//
+// The statements are separated by ';' at the end of line.
+type Migration struct {
+	Version  uint
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of Up
+}
+
+// LoadMigrations reads the migrations of dir from fsys, e.g. embed.FS
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migration dir: %w", err)
+	}
+
+	table := make(map[uint]*Migration)
+	for _, entry := range entries {
+		filename := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(filename, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(filename, ".sql")
+		base, direction, ok := cutLast(base, ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %v: filename must end with .up.sql or .down.sql", filename)
+		}
+		rawVersion, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %v: filename must be {version}_{name}", filename)
+		}
+		version, err := strconv.ParseUint(rawVersion, 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %v: version must be a positive integer", filename)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, filename))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %v: %w", filename, err)
+		}
+
+		migration, exist := table[uint(version)]
+		if !exist {
+			migration = &Migration{Version: uint(version), Name: name}
+			table[uint(version)] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration version %v is duplicated: %v, %v", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(table))
+	for _, migration := range table {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %v_%v: up.sql is required", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+func cutLast(s string, sep string) (before string, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+func splitStatements(script string) []string {
+	var statements []string
+	var builder strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		builder.WriteString(line)
+		builder.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(builder.String()))
+			builder.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(builder.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+//
+
+type MigrationStatus struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Dirty     bool       `json:"dirty"` // the checksum is changed after applied
+}
+
+// NewMigrator applies the migrations to MySQL.
+//
+// The applied versions are recorded in schema_migrations,
+// and the MySQL named lock (GET_LOCK) prevents concurrent runs, e.g. multiple pods start at the same time.
+// The lock is held by a dedicated connection, so it is released even if the process crashes.
+//
+// Before each operation, the checksums of applied migrations are verified,
+// the applied sql file should not be modified, add a new version instead.
+func NewMigrator(db *sql.DB, migrations []Migration, logger *slog.Logger) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(applied map[uint]appliedMigration) error {
+		latest := uint(0)
+		for version := range applied {
+			latest = max(latest, version)
+		}
+		if latest == 0 {
+			return nil
+		}
+		return m.down(ctx, m.find(latest))
+	})
+}
+
+// To migrates up or down to the version, 0 indicates reverting all migrations.
+func (m *Migrator) To(ctx context.Context, version uint) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("migration version %v does not exist", version)
+	}
+
+	return m.withLock(ctx, func(applied map[uint]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := &m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				err := m.down(ctx, migration)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range m.migrations {
+			migration := &m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				err := m.up(ctx, migration)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	err := m.createTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.appliedAt
+			status.Dirty = record.checksum != migration.Checksum
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+//
+
+const (
+	migrationTable = "schema_migrations"
+
+	// the named lock is global in the MySQL server, so it is scoped by database
+	migrationLockName = `CONCAT(DATABASE(), '.` + migrationTable + `')`
+)
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func (m *Migrator) find(version uint) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createTables(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+			version    BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+			name       VARCHAR(255)    NOT NULL,
+			checksum   CHAR(64)        NOT NULL,
+			applied_at DATETIME(3)     NOT NULL
+		)`,
+	}
+	for _, statement := range statements {
+		_, err := m.db.ExecContext(ctx, statement)
+		if err != nil {
+			return fmt.Errorf("create migration table: %w", err)
+		}
+	}
+	return nil
+}
+
+// withLock uses the MySQL named lock on a dedicated connection,
+// the lock is released by MySQL when the connection is closed, e.g. the process crashes.
+func (m *Migrator) withLock(ctx context.Context, fn func(applied map[uint]appliedMigration) error) (err error) {
+	err = m.createTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get migration lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(`+migrationLockName+`, 0)`).Scan(&acquired)
+	if err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		var holder sql.NullInt64
+		Err := conn.QueryRowContext(ctx, `SELECT IS_USED_LOCK(`+migrationLockName+`)`).Scan(&holder)
+		if Err == nil && holder.Valid {
+			return fmt.Errorf("%w: connection id %v", ErrMigrationLocked, holder.Int64)
+		}
+		return ErrMigrationLocked
+	}
+	defer func() {
+		_, Err := conn.ExecContext(context.WithoutCancel(ctx), `DO RELEASE_LOCK(`+migrationLockName+`)`)
+		if Err != nil {
+			err = errors.Join(err, fmt.Errorf("release migration lock: %w", Err))
+		}
+	}()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.verify(applied)
+	if err != nil {
+		return err
+	}
+	return fn(applied)
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint]appliedMigration, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT version, checksum, applied_at FROM `+migrationTable)
+	if err != nil {
+		return nil, fmt.Errorf("query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[uint]appliedMigration)
+	for rows.Next() {
+		var version uint
+		var record appliedMigration
+		err := rows.Scan(&version, &record.checksum, &record.appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan applied migration: %w", err)
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) verify(applied map[uint]appliedMigration) error {
+	var errs []error
+	for version, record := range applied {
+		migration := m.find(version)
+		if migration == nil {
+			errs = append(errs, fmt.Errorf("%w: version %v", ErrMigrationMissing, version))
+			continue
+		}
+		if migration.Checksum != record.checksum {
+			errs = append(errs, fmt.Errorf("%w: %v_%v", ErrMigrationChecksum, migration.Version, migration.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// up and down are not wrapped by transaction, since MySQL DDL causes implicit commit.
+// If a statement fails, the version is not recorded, and the previous statements must be fixed manually.
+
+func (m *Migrator) up(ctx context.Context, migration *Migration) error {
+	err := m.exec(ctx, migration, migration.Up)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx,
+		`INSERT INTO `+migrationTable+` (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		migration.Version, migration.Name, migration.Checksum, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("record migration %v_%v: %w", migration.Version, migration.Name, err)
+	}
+	m.logger.Info("migration up", slog.Uint64("version", uint64(migration.Version)), slog.String("name", migration.Name))
+	return nil
+}
+
+func (m *Migrator) down(ctx context.Context, migration *Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %v_%v: down.sql does not exist", migration.Version, migration.Name)
+	}
+	err := m.exec(ctx, migration, migration.Down)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx, `DELETE FROM `+migrationTable+` WHERE version = ?`, migration.Version)
+	if err != nil {
+		return fmt.Errorf("delete migration %v_%v: %w", migration.Version, migration.Name, err)
+	}
+	m.logger.Info("migration down", slog.Uint64("version", uint64(migration.Version)), slog.String("name", migration.Name))
+	return nil
+}
+
+func (m *Migrator) exec(ctx context.Context, migration *Migration, script string) error {
+	for _, statement := range splitStatements(script) {
+		_, err := m.db.ExecContext(ctx, statement)
+		if err != nil {
+			return fmt.Errorf("migration %v_%v: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package utility
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.up.sql":      {Data: []byte("CREATE INDEX idx_users_email ON users (email);")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (\n  id CHAR(26)\n);\n-- comment\nCREATE TABLE roles (id INT);\n")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE roles;\nDROP TABLE users;\n")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, uint(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.Equal(t, []string{"CREATE TABLE users (\n  id CHAR(26)\n);", "CREATE TABLE roles (id INT);"}, splitStatements(migrations[0].Up))
+	assert.Equal(t, []string{"DROP TABLE roles;", "DROP TABLE users;"}, splitStatements(migrations[0].Down))
+
+	assert.Equal(t, uint(2), migrations[1].Version)
+	assert.Empty(t, migrations[1].Down)
+
+	fsys["migrations/0002_rename.down.sql"] = &fstest.MapFile{Data: []byte("")}
+	_, err = LoadMigrations(fsys, "migrations")
+	assert.ErrorContains(t, err, "duplicated")
+}
+
+func TestMigrator_lock(t *testing.T) {
+	server := &lockServer{}
+	other := sql.OpenDB(server)
+	db := sql.OpenDB(server)
+	defer db.Close()
+
+	migrations := []Migration{{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id INT);"}}
+	migrator := NewMigrator(db, migrations, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// another process holds the lock
+	conn, err := other.Conn(context.Background())
+	require.NoError(t, err)
+	var acquired int
+	require.NoError(t, conn.QueryRowContext(context.Background(), `SELECT GET_LOCK(`+migrationLockName+`, 0)`).Scan(&acquired))
+	conn.Close()
+
+	err = migrator.Up(context.Background())
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+	assert.ErrorContains(t, err, "connection id 1")
+	assert.NotContains(t, server.statements, "CREATE TABLE users (id INT);")
+
+	// the process crashes, MySQL releases the lock of closed connection
+	require.NoError(t, other.Close())
+
+	err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, server.statements, "CREATE TABLE users (id INT);")
+	assert.Nil(t, server.holder, "the lock is released after migration")
+}
+
+// lockServer simulates the named lock of MySQL, it is held by connection
+type lockServer struct {
+	mu         sync.Mutex
+	connQty    int64
+	holder     *lockConn
+	statements []string
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connQty++
+	return &lockConn{server: s, id: s.connQty}, nil
+}
+
+func (s *lockServer) Driver() driver.Driver { return nil }
+
+type lockConn struct {
+	server *lockServer
+	id     int64
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *lockConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.server.holder == c {
+		c.server.holder = nil
+	}
+	return nil
+}
+
+func (c *lockConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if strings.HasPrefix(query, "DO RELEASE_LOCK") && c.server.holder == c {
+		c.server.holder = nil
+	}
+	c.server.statements = append(c.server.statements, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *lockConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		if c.server.holder != nil && c.server.holder != c {
+			return &lockRows{columns: []string{"lock"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		c.server.holder = c
+		return &lockRows{columns: []string{"lock"}, values: [][]driver.Value{{int64(1)}}}, nil
+	case strings.HasPrefix(query, "SELECT IS_USED_LOCK"):
+		if c.server.holder == nil {
+			return &lockRows{columns: []string{"holder"}, values: [][]driver.Value{{nil}}}, nil
+		}
+		return &lockRows{columns: []string{"holder"}, values: [][]driver.Value{{c.server.holder.id}}}, nil
+	}
+	// schema_migrations is empty
+	return &lockRows{columns: []string{"version", "checksum", "applied_at"}}, nil
+}
+
+type lockRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *lockRows) Columns() []string { return r.columns }
+
+func (r *lockRows) Close() error { return nil }
+
+func (r *lockRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -2,8 +2,11 @@ package utility
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 
@@ -16,6 +19,8 @@ import (
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
 )
 
 type O11YConfig struct {
@@ -24,12 +29,20 @@ type O11YConfig struct {
 	TraceHost   string  `yaml:"TraceHost"`
 	TracePort   string  `yaml:"TracePort"`
 	SampleRate  float64 `yaml:"SampleRate"` // 0 ~ 1
+	EnableLog   bool    `yaml:"EnableLog"`
+	LogHost     string  `yaml:"LogHost"`
+	LogPort     string  `yaml:"LogPort"`
+	AdminToken  string  `yaml:"AdminToken"` // bearer token of admin api, empty indicates only hack is allowed
 }
 
 func (o O11YConfig) TraceAddress() string {
 	return fmt.Sprintf("%v:%v", o.TraceHost, o.TracePort)
 }
 
+func (o O11YConfig) LogAddress() string {
+	return fmt.Sprintf("%v:%v", o.LogHost, o.LogPort)
+}
+
 //
 
 func InitO11YTracer(conf *O11YConfig, shutdown *Shutdown, svcName string) error {
@@ -57,34 +70,104 @@ func InitO11YTracer(conf *O11YConfig, shutdown *Shutdown, svcName string) error
 	)
 	otel.SetTracerProvider(provider)
 
-	shutdown.AddPriorityShutdownAction(2, "trace", func() error {
-		return provider.Shutdown(context.Background())
-	})
+	shutdown.AddPriorityShutdownActionCtx(TelemetryPriority, "trace", provider.Shutdown)
 	return nil
 }
 
-func ServeO11YMetric(port string, shutdown *Shutdown, logger *slog.Logger) {
+// InitO11YLogger creates a slog.Handler which exports logs to OTLP receiver,
+// it returns nil handler if EnableLog is false.
+//
+// The handler should be fanned out by wlog.LoggerFactory.
+func InitO11YLogger(conf *O11YConfig, shutdown *Shutdown, svcName string, lvl slog.Leveler) (slog.Handler, error) {
+	if !conf.EnableLog {
+		return nil, nil
+	}
+
+	handler, err := wlog.NewOTLPHandler(&wlog.OTLPConfig{
+		Endpoint:    conf.LogAddress(),
+		Insecure:    true,
+		ServiceName: svcName,
+	}, lvl)
+	if err != nil {
+		return nil, err
+	}
+
+	shutdown.AddPriorityShutdownActionCtx(TelemetryPriority, "otlp_log", handler.Shutdown)
+	return handler, nil
+}
+
+// O11YServerHook serves pprof, fgprof, metric, health probes, error catalog and admin api on the same port,
+// the port should not be exposed to the public network.
+func O11YServerHook(
+	port string,
+	shutdown *Shutdown,
+	health *HealthRegistry,
+	registry *ErrorRegistry,
+	guard AdminGuard,
+	event *wlog.EventLogger,
+	logger *slog.Logger,
+) LifecycleHook {
 	// pprof
 	// https://cs.opensource.google/go/go/+/refs/tags/go1.23.0:src/net/http/pprof/pprof.go;l=100-104
 	// https://pkg.go.dev/runtime/pprof#Profile
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", http.DefaultServeMux)
 
 	// fgprof
 	// https://github.com/felixge/fgprof?tab=readme-ov-file#how-it-works
-	http.Handle("/debug/fgprof", fgprof.Handler())
+	mux.Handle("/debug/fgprof", fgprof.Handler())
 
 	// metric
-	http.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
-
-	server := &http.Server{Addr: "0.0.0.0:" + port, Handler: http.DefaultServeMux}
-	shutdown.AddPriorityShutdownAction(2, "metric_&_pprof", func() error {
-		return server.Shutdown(context.Background())
-	})
-
-	go func() {
-		logger.Info("pprof start", slog.String("url", "http://0.0.0.0:"+port+"/debug/pprof"))
-		logger.Info("fgprof start", slog.String("url", "http://0.0.0.0:"+port+"/debug/fgprof?seconds=1"))
-		logger.Info("metric start", slog.String("url", "http://0.0.0.0:"+port+"/metrics"))
-		err := server.ListenAndServe()
+	mux.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	// health
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", health.ReadinessHandler())
+
+	// error catalog
+	if registry != nil {
+		mux.Handle("/errors", registry.CatalogHandler())
+	}
+
+	// admin
+	mux.Handle("/admin/shutdown", ShutdownAdminHandler(guard, shutdown, event))
+
+	server := &http.Server{Addr: "0.0.0.0:" + port, Handler: mux}
+
+	return LifecycleHook{
+		Name:     "metric_&_pprof",
+		Priority: 2,
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("pprof start", slog.String("url", "http://0.0.0.0:"+port+"/debug/pprof"))
+			logger.Info("fgprof start", slog.String("url", "http://0.0.0.0:"+port+"/debug/fgprof?seconds=1"))
+			logger.Info("metric start", slog.String("url", "http://0.0.0.0:"+port+"/metrics"))
+			logger.Info("health start", slog.String("url", "http://0.0.0.0:"+port+"/readyz"))
+			if registry != nil {
+				logger.Info("error catalog start", slog.String("url", "http://0.0.0.0:"+port+"/errors"))
+			}
+			go func() {
+				err := server.Serve(listener)
+				if !errors.Is(err, http.ErrServerClosed) {
+					shutdown.Notify(err)
+				}
+			}()
+			return nil
+		},
+		Stop: server.Shutdown,
+	}
+}
+
+// ServeO11YMetric starts O11YServerHook without Lifecycle,
+// the admin endpoints always deny the requests because AdminGuard is empty.
+func ServeO11YMetric(port string, shutdown *Shutdown, health *HealthRegistry, logger *slog.Logger) {
+	hook := O11YServerHook(port, shutdown, health, nil, AdminGuard{}, wlog.NewEventLogger(io.Discard), logger)
+	err := NewLifecycle(shutdown, logger).Append(hook).Start(context.Background())
+	if err != nil {
 		shutdown.Notify(err)
-	}()
+	}
 }
@@ -0,0 +1,41 @@
+package utility
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestInitO11YTracer_stopAfterDatastore(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(provider) })
+
+	shutdown := NewShutdown(context.Background(), 0, nil)
+
+	conf := &O11YConfig{EnableTrace: true, TraceHost: "127.0.0.1", TracePort: "4318"}
+	require.NoError(t, InitO11YTracer(conf, shutdown, "observability_test"))
+
+	traceState := func() ComponentState {
+		components := shutdown.Summary().Components
+		i := slices.IndexFunc(components, func(report ComponentReport) bool { return report.Name == "trace" })
+		require.GreaterOrEqual(t, i, 0)
+		return components[i].State
+	}
+
+	// the tracer is registered first, but it waits until mysql is stopped
+	var stateOnMysqlStop ComponentState
+	shutdown.AddPriorityShutdownAction(2, "mysql", func() error {
+		stateOnMysqlStop = traceState()
+		return nil
+	})
+
+	shutdown.Notify(nil)
+	shutdown.Serve()
+
+	assert.Equal(t, ComponentPending, stateOnMysqlStop)
+	assert.Equal(t, ComponentStopped, traceState())
+}
@@ -3,10 +3,14 @@ package utility
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -58,42 +62,188 @@ func NewShutdown(countdown context.Context, waitSeconds int, logger *slog.Logger
 
 		waitSeconds: waitSeconds,
 		done:        make(chan struct{}),
+
+		exit: os.Exit,
 	}
 
 	return shutdown
 }
 
+// ShutdownComponent describes a component which is stopped gracefully when a shutdown is triggered.
+//
+// The components are stopped in topological order,
+// a component is stopped only after all components which depend on it are stopped.
+// Independent components are stopped in parallel.
+//
+// Example:
+//
+//	http server -> mq consumer -> outbox relay -> mysql, redis -> tracer
+//
+//	// the dependencies must be registered first
+//	shutdown.AddShutdownComponent(ShutdownComponent{Name: "tracer"})
+//	shutdown.AddShutdownComponent(ShutdownComponent{Name: "mysql", DependsOn: []string{"tracer"}})
+//	shutdown.AddShutdownComponent(ShutdownComponent{Name: "redis", DependsOn: []string{"tracer"}})
+//	shutdown.AddShutdownComponent(ShutdownComponent{Name: "outbox", DependsOn: []string{"mysql", "redis"}})
+//	shutdown.AddShutdownComponent(ShutdownComponent{Name: "consumer", DependsOn: []string{"outbox"}})
+//	shutdown.AddShutdownComponent(ShutdownComponent{Name: "http", DependsOn: []string{"consumer"}})
+type ShutdownComponent struct {
+	Name string
+
+	// DependsOn lists the names of components used by this component,
+	// they are stopped after this component is stopped.
+	// The name must refer to a registered component, see ErrShutdownComponentNotFound
+	DependsOn []string
+
+	// Timeout limits the duration of Stop, <= 0 indicates no limit.
+	// When it elapses, the dependencies continue to stop.
+	Timeout time.Duration
+
+	// Stop receives a ctx which carries the budget of this component,
+	// see Shutdown.AddShutdownActionCtx
+	Stop func(ctx context.Context) error
+}
+
 type component struct {
-	name string
-	stop func() error
+	ShutdownComponent
+	seq int
+
+	// priority is -1 when the component is registered without priority
+	priority int
+
+	// guarded by Shutdown.stateMu
+	state    ComponentState
+	err      error
+	duration time.Duration
 }
 
+// LowestPriority is used by AddShutdownAction and AddShutdownActionCtx,
+// the components with larger priority number are stopped after them.
+const LowestPriority = 2
+
+// TelemetryPriority is used by tracer, log exporter and event log,
+// they are stopped after the datastores, so that the spans and logs emitted during shutdown are not lost.
+const TelemetryPriority = LowestPriority + 1
+
+var (
+	ErrShutdownComponentDuplicated = errors.New("shutdown component duplicated")
+	ErrShutdownComponentCycle      = errors.New("shutdown component dependency cycle")
+	ErrShutdownComponentNotFound   = errors.New("shutdown component dependency not found")
+)
+
 type Shutdown struct {
 	osSig     chan os.Signal
 	countdown context.Context
 	notify    context.CancelCauseFunc
 
 	waitSeconds int
+	drainDelay  time.Duration
 	done        chan struct{}
+	draining    atomic.Bool
 
 	logger *slog.Logger
 	mu     sync.Mutex
 
-	// The fields `components` use an array of size 3,
-	// representing three priority levels for shutdown process.
-	//
-	// priority 0 is the highest, and priority 2 is the lowest.
+	// exit is replaced in tests
+	exit         func(code int)
+	stateMu      sync.Mutex
+	summary      ShutdownSummary
+	order        []*component
+	stopDeadline time.Time
+
+	// components form a dependency graph,
+	// the edge of graph is ShutdownComponent.DependsOn
 	componentQty int
-	components   [3][]component
+	components   map[string]*component
+}
+
+// AddShutdownComponent registers a component into the dependency graph.
+// It returns error when the name is duplicated, a dependency is not registered
+// or the dependencies form a cycle, in this case the component is not registered.
+func (s *Shutdown) AddShutdownComponent(comp ShutdownComponent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	return s.addComponent(&component{ShutdownComponent: comp, priority: -1})
+}
+
+func (s *Shutdown) addComponent(comp *component) error {
+	if s.components == nil {
+		s.components = make(map[string]*component)
+	}
+
+	if _, exist := s.components[comp.Name]; exist {
+		return fmt.Errorf("name=%v: %w", comp.Name, ErrShutdownComponentDuplicated)
+	}
+
+	for _, dependency := range comp.DependsOn {
+		if _, exist := s.components[dependency]; !exist && dependency != comp.Name {
+			return fmt.Errorf("name=%v dependency=%v: %w", comp.Name, dependency, ErrShutdownComponentNotFound)
+		}
+	}
+
+	if path, found := s.findPath(comp.DependsOn, comp.Name, []string{comp.Name}); found {
+		return fmt.Errorf("path=%v: %w", strings.Join(path, " -> "), ErrShutdownComponentCycle)
+	}
+
+	s.componentQty++
+	comp.seq = s.componentQty
+	comp.DependsOn = slices.Clone(comp.DependsOn)
+	s.components[comp.Name] = comp
+
+	s.stateMu.Lock()
+	comp.state = ComponentRunning
+	s.order = append(s.order, comp)
+	s.stateMu.Unlock()
+	return nil
+}
+
+// findPath uses DFS to find whether target is reachable from names
+func (s *Shutdown) findPath(names []string, target string, path []string) ([]string, bool) {
+	for _, name := range names {
+		next := append(slices.Clone(path), name)
+		if name == target {
+			return next, true
+		}
+		comp, ok := s.components[name]
+		if !ok {
+			continue
+		}
+		if found, ok := s.findPath(comp.DependsOn, target, next); ok {
+			return found, true
+		}
+	}
+	return nil, false
 }
 
 // AddPriorityShutdownAction registers a shutdown process with a given priority.
 //
+// It is built on top of the dependency graph,
+// a component with higher priority depends on all components with lower priority,
+// so that it is stopped first.
+//
 // Parameters:
-//   - priority: Priority of the component (0 is the highest, and 2 is the lowest).
-//   - name: Name of the component.
+//   - priority: Priority of the component (0 is the highest, the larger the number the lower the priority).
+//   - name: Name of the component. If the name is duplicated, a sequence number is appended.
 //   - stopAction: Function to execute during shutdown.
 func (s *Shutdown) AddPriorityShutdownAction(priority uint, name string, stopAction func() error) *Shutdown {
+	return s.AddPriorityShutdownActionCtx(priority, name, func(context.Context) error {
+		return stopAction()
+	})
+}
+
+// AddPriorityShutdownActionCtx is the same as AddPriorityShutdownAction,
+// but stopAction receives a ctx which carries the remaining shutdown budget.
+//
+// The method is chainable, so the registration error is not returned,
+// it is logged and the stopAction is not registered.
+// Use AddPriorityShutdownComponent to receive the error.
+func (s *Shutdown) AddPriorityShutdownActionCtx(priority uint, name string, stopAction func(ctx context.Context) error) *Shutdown {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -103,16 +253,114 @@ func (s *Shutdown) AddPriorityShutdownAction(priority uint, name string, stopAct
 	default:
 	}
 
-	s.componentQty++
-	comp := component{name: name, stop: stopAction}
-	s.components[priority] = append(s.components[priority], comp)
+	uniqueName := name
+	for i := 2; s.components[uniqueName] != nil; i++ {
+		uniqueName = fmt.Sprintf("%v#%v", name, i)
+	}
+
+	err := s.addPriorityComponent(priority, ShutdownComponent{Name: uniqueName, Stop: stopAction})
+	if err != nil {
+		s.logger.Error("register shutdown component failed",
+			slog.String("component", uniqueName),
+			slog.Int("priority", int(priority)),
+			slog.Any("err", err),
+		)
+	}
 	return s
 }
 
+// AddPriorityShutdownComponent is the combination of AddPriorityShutdownAction and AddShutdownComponent,
+// the component is ordered by priority and its own DependsOn.
+func (s *Shutdown) AddPriorityShutdownComponent(priority uint, comp ShutdownComponent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	return s.addPriorityComponent(priority, comp)
+}
+
+func (s *Shutdown) addPriorityComponent(priority uint, comp ShutdownComponent) error {
+	node := &component{
+		ShutdownComponent: comp,
+		priority:          int(priority),
+	}
+	node.DependsOn = slices.Clone(comp.DependsOn)
+
+	var dependents []*component
+	for _, other := range s.components {
+		if other.priority < 0 {
+			continue
+		}
+		if other.priority > node.priority {
+			node.DependsOn = append(node.DependsOn, other.Name)
+		}
+		if other.priority < node.priority {
+			dependents = append(dependents, other)
+		}
+	}
+
+	for _, other := range dependents {
+		if path, found := s.findPath(node.DependsOn, other.Name, []string{other.Name, node.Name}); found {
+			return fmt.Errorf("path=%v: %w", strings.Join(path, " -> "), ErrShutdownComponentCycle)
+		}
+	}
+
+	err := s.addComponent(node)
+	if err != nil {
+		return err
+	}
+	for _, other := range dependents {
+		other.DependsOn = append(other.DependsOn, node.Name)
+	}
+	return nil
+}
+
+// RemoveShutdownComponent unregisters a component,
+// it is used when the component is stopped by other means, e.g. rollback of Lifecycle.
+func (s *Shutdown) RemoveShutdownComponent(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	if _, exist := s.components[name]; !exist {
+		return
+	}
+	delete(s.components, name)
+	s.stateMu.Lock()
+	s.order = slices.DeleteFunc(s.order, func(comp *component) bool {
+		return comp.Name == name
+	})
+	s.stateMu.Unlock()
+	for _, other := range s.components {
+		other.DependsOn = slices.DeleteFunc(other.DependsOn, func(dependency string) bool {
+			return dependency == name
+		})
+	}
+}
+
 // AddShutdownAction This method registers a shutdown process to be stopped gracefully when a shutdown is triggered.
 func (s *Shutdown) AddShutdownAction(name string, stopAction func() error) *Shutdown {
-	const LowestPriority = len(s.components) - 1
-	return s.AddPriorityShutdownAction(uint(LowestPriority), name, stopAction)
+	return s.AddPriorityShutdownAction(LowestPriority, name, stopAction)
+}
+
+// AddShutdownActionCtx registers a shutdown process whose ctx carries the remaining shutdown budget.
+//
+// The budget is shared with the components which are stopped later,
+// e.g. when http server, mq consumer and mysql are stopped in order,
+// http server gets 1/3 of remaining time, so that a stuck http drain can not starve mysql close.
+// The ctx is also limited by ShutdownComponent.Timeout.
+func (s *Shutdown) AddShutdownActionCtx(name string, stopAction func(ctx context.Context) error) *Shutdown {
+	return s.AddPriorityShutdownActionCtx(LowestPriority, name, stopAction)
 }
 
 // Notify is used to trigger an immediate shutdown in case of a critical error.
@@ -129,23 +377,78 @@ func (s *Shutdown) WaitChannel() <-chan struct{} {
 	return s.done
 }
 
+// Drain triggers a graceful shutdown on purpose, e.g. blue/green deployment,
+// it is not treated as an error like Notify.
+func (s *Shutdown) Drain(reason string) {
+	s.Notify(&drainCause{reason: reason})
+}
+
+type drainCause struct {
+	reason string
+}
+
+func (d *drainCause) Error() string {
+	return "drain: " + d.reason
+}
+
+// Draining reports whether the shutdown is triggered,
+// it becomes true before any component is stopped.
+func (s *Shutdown) Draining() bool {
+	return s.draining.Load()
+}
+
+// SetDrainDelay keeps the components running for a while after Draining becomes true,
+// so that the load balancer observes the failed readiness probe and removes the traffic
+// before the http servers are stopped.
+//
+// The delay is a part of waitSeconds, <= 0 indicates no delay.
+func (s *Shutdown) SetDrainDelay(delay time.Duration) *Shutdown {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainDelay = delay
+	return s
+}
+
+func (s *Shutdown) waitDrainDelay() {
+	delay := s.drainDelay
+	if deadline := s.deadline(); !deadline.IsZero() {
+		delay = min(delay, time.Until(deadline))
+	}
+	if delay <= 0 {
+		return
+	}
+	s.logger.Info("shutdown wait for drain", slog.String("delay", delay.String()))
+	time.Sleep(delay)
+}
+
 func (s *Shutdown) Serve() {
+	var trigger, cause string
 	select {
 	case sig := <-s.osSig:
+		trigger, cause = "external", sig.String()
 		s.logger.Info("recv os signal",
-			slog.String("trigger", "external"),
+			slog.String("trigger", trigger),
 			slog.Any("signal", sig),
 		)
 
 	case <-s.countdown.Done():
+		trigger = "internal"
 		err := context.Cause(s.countdown)
-		if errors.Is(err, context.Canceled) {
+		var drain *drainCause
+		if errors.As(err, &drain) {
+			trigger, cause = "admin", drain.reason
+			s.logger.Info("recv drain request",
+				slog.String("trigger", trigger),
+				slog.String("reason", cause),
+			)
+		} else if errors.Is(err, context.Canceled) {
 			s.logger.Info("recv go context",
-				slog.String("trigger", "internal"),
+				slog.String("trigger", trigger),
 			)
 		} else {
+			cause = err.Error()
 			s.logger.Error("recv go context",
-				slog.String("trigger", "internal"),
+				slog.String("trigger", trigger),
 				slog.Any("err", err),
 			)
 		}
@@ -153,6 +456,7 @@ func (s *Shutdown) Serve() {
 	case <-s.done:
 		return
 	}
+	s.draining.Store(true)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -164,9 +468,11 @@ func (s *Shutdown) Serve() {
 	}
 
 	defer close(s.done)
+	go s.forceExitOnSignal()
 
-	s.logger.Info("shutdown start", slog.Int("qty", s.componentQty))
+	s.logger.Info("shutdown start", slog.Int("qty", len(s.components)))
 	start := time.Now()
+	s.beginSummary(trigger, cause, start)
 
 	var timeout <-chan time.Time
 	if s.waitSeconds > 0 {
@@ -175,6 +481,7 @@ func (s *Shutdown) Serve() {
 
 	finish := make(chan struct{}, 1)
 	go func() {
+		s.waitDrainDelay()
 		s.terminate()
 		finish <- struct{}{}
 	}()
@@ -182,45 +489,144 @@ func (s *Shutdown) Serve() {
 	select {
 	case <-timeout:
 		duration := time.Since(start)
-		s.logger.Error("shutdown failed because timeout", slog.String("duration", duration.String()))
+		s.finishSummary(true, duration)
+		s.logger.Error("shutdown failed because timeout",
+			slog.String("duration", duration.String()),
+			slog.Any("unfinished", s.Summary().Unfinished()),
+			dumpGoroutines(),
+		)
 	case <-finish:
 		duration := time.Since(start)
+		s.finishSummary(false, duration)
 		s.logger.Info("shutdown finish", slog.String("duration", duration.String()))
 	}
 }
 
+// terminate stops components in topological order,
+// each component waits for the components which depend on it.
 func (s *Shutdown) terminate() {
-	seq := 0
+	finished := make(map[string]chan struct{}, len(s.components))
+	dependents := make(map[string][]string, len(s.components))
+	for name, comp := range s.components {
+		finished[name] = make(chan struct{})
+		for _, dependency := range comp.DependsOn {
+			dependents[dependency] = append(dependents[dependency], name)
+		}
+	}
+
+	heights := make(map[string]int, len(s.components))
+	for name := range s.components {
+		s.height(name, heights)
+	}
+
 	wg := sync.WaitGroup{}
-	for priority, components := range s.components {
-		for j := range components {
-			seq += 1
-			wg.Add(1)
-			comp := components[j]
-
-			go func(sequence int) {
-				defer wg.Done()
-
-				logger := s.logger.With(
-					slog.Int("no.", sequence),
-					slog.Int("priority", priority),
-					slog.String("component", comp.name),
-				)
-
-				logger.Info("terminate start")
-				start := time.Now()
-
-				err := comp.stop()
-				if err != nil {
-					logger.Error("terminate fail", slog.Any("err", err))
-					return
-				}
-
-				duration := time.Since(start)
-				logger.Info("terminate finish", slog.String("duration", duration.String()))
-
-			}(seq)
+	for name, comp := range s.components {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(finished[name])
+
+			for _, dependent := range dependents[name] {
+				<-finished[dependent]
+			}
+			s.stopComponent(comp, s.height(name, heights))
+		}()
+	}
+	wg.Wait()
+}
+
+// height is the number of layers which are stopped after the component,
+// it decides the share of remaining budget.
+func (s *Shutdown) height(name string, memo map[string]int) int {
+	h, ok := memo[name]
+	if ok {
+		return h
+	}
+	for _, dependency := range s.components[name].DependsOn {
+		if _, exist := s.components[dependency]; exist {
+			h = max(h, s.height(dependency, memo)+1)
 		}
-		wg.Wait()
+	}
+	memo[name] = h
+	return h
+}
+
+func (s *Shutdown) stopComponent(comp *component, height int) {
+	attrs := []any{
+		slog.Int("no.", comp.seq),
+		slog.String("component", comp.Name),
+	}
+	if comp.priority >= 0 {
+		attrs = append(attrs, slog.Int("priority", comp.priority))
+	}
+	logger := s.logger.With(attrs...)
+
+	limit := comp.Timeout
+	if deadline := s.deadline(); !deadline.IsZero() {
+		share := time.Until(deadline) / time.Duration(height+1)
+		if limit <= 0 || share < limit {
+			limit = share
+		}
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if limit > 0 {
+		ctx, cancel = context.WithTimeout(ctx, limit)
+	}
+	defer cancel()
+
+	logger.Info("terminate start")
+	start := time.Now()
+	s.setComponentState(comp, ComponentStopping, nil, 0)
+
+	result := make(chan error, 1)
+	go func() {
+		if comp.Stop == nil {
+			result <- nil
+			return
+		}
+		result <- comp.Stop(ctx)
+	}()
+
+	// Stop usually returns ctx.Err() when ctx is done,
+	// so the state is decided by ctx.Err() rather than which channel is selected.
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		select {
+		case err = <-result:
+		default:
+			err = ctx.Err()
+		}
+	}
+
+	duration := time.Since(start)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		s.setComponentState(comp, ComponentTimeout, err, duration)
+		logger.Error("terminate timeout", slog.String("timeout", limit.String()), slog.Any("err", err))
+	case err != nil:
+		s.setComponentState(comp, ComponentFailed, err, duration)
+		logger.Error("terminate fail", slog.Any("err", err))
+	default:
+		s.setComponentState(comp, ComponentStopped, nil, duration)
+		logger.Info("terminate finish", slog.String("duration", duration.String()))
+	}
+}
+
+// StopWithCtx runs stop which doesn't accept ctx,
+// it returns ctx.Err() when ctx is done before stop returns, and stop keeps running in background.
+func StopWithCtx(ctx context.Context, stop func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- stop()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
@@ -0,0 +1,66 @@
+package utility
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+const (
+	ShutdownDrainAction = "shutdown.drain"
+	ShutdownResource    = "shutdown"
+)
+
+// ShutdownAdminHandler serves the admin api of Shutdown.
+//
+//	GET  /admin/shutdown              list components and progress
+//	POST /admin/shutdown?reason=xxx   drain the pod without os signal
+//
+// The drain is a security action, it is recorded by event logger.
+func ShutdownAdminHandler(guard AdminGuard, shutdown *Shutdown, event *wlog.EventLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		actor, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+		if !guard.Allow(r.URL.Query().Get("hack"), r.Header.Get("Authorization")) {
+			if r.Method == http.MethodPost {
+				event.Emit(ctx, wlog.Event{
+					Actor:    actor,
+					Action:   ShutdownDrainAction,
+					Resource: ShutdownResource,
+					Outcome:  wlog.OutcomeDenied,
+					Reason:   "admin guard failed",
+				})
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		code := http.StatusOK
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			reason := r.URL.Query().Get("reason")
+			if reason == "" {
+				reason = "admin api"
+			}
+			event.Emit(ctx, wlog.Event{
+				Actor:    actor,
+				Action:   ShutdownDrainAction,
+				Resource: ShutdownResource,
+				Detail:   map[string]string{"reason": reason},
+			})
+			shutdown.Drain(reason)
+			code = http.StatusAccepted
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(shutdown.Summary())
+	})
+}
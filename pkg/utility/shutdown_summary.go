@@ -0,0 +1,195 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+)
+
+type ComponentState string
+
+const (
+	ComponentRunning  ComponentState = "running"
+	ComponentPending  ComponentState = "pending"
+	ComponentStopping ComponentState = "stopping"
+	ComponentStopped  ComponentState = "stopped"
+	ComponentFailed   ComponentState = "failed"
+	ComponentTimeout  ComponentState = "timeout"
+)
+
+// ShutdownForceExitCode is used when a second os signal is received during shutdown,
+// it is distinct from the exit code 1 of normal failure.
+const ShutdownForceExitCode = 3
+
+type ComponentReport struct {
+	Name     string         `json:"name"`
+	Priority int            `json:"priority"` // -1 indicates the component is registered without priority
+	State    ComponentState `json:"state"`
+	Duration time.Duration  `json:"duration"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// ShutdownSummary is the result of Shutdown.Serve, it can be logged or asserted in tests.
+type ShutdownSummary struct {
+	Draining   bool              `json:"draining"`
+	Trigger    string            `json:"trigger,omitempty"` // external, internal or admin
+	Cause      string            `json:"cause,omitempty"`
+	Start      time.Time         `json:"start"`
+	Duration   time.Duration     `json:"duration"`
+	TimedOut   bool              `json:"timed_out"`
+	Components []ComponentReport `json:"components"`
+}
+
+// Unfinished returns the names of components which are not stopped successfully.
+// It is meaningful only after the shutdown is triggered.
+func (summary ShutdownSummary) Unfinished() []string {
+	names := make([]string, 0)
+	for _, report := range summary.Components {
+		if report.State != ComponentStopped {
+			names = append(names, report.Name)
+		}
+	}
+	return names
+}
+
+func (summary ShutdownSummary) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("trigger", summary.Trigger),
+		slog.String("duration", summary.Duration.String()),
+		slog.Bool("timed_out", summary.TimedOut),
+		slog.Any("unfinished", summary.Unfinished()),
+	)
+}
+
+// Summary returns a snapshot of shutdown progress,
+// the state of components is running before the shutdown is triggered.
+func (s *Shutdown) Summary() ShutdownSummary {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	summary := s.summary
+	summary.Draining = s.Draining()
+	summary.Components = make([]ComponentReport, 0, len(s.order))
+	for _, comp := range s.order {
+		report := ComponentReport{
+			Name:     comp.Name,
+			Priority: comp.priority,
+			State:    comp.state,
+			Duration: comp.duration,
+		}
+		if comp.err != nil {
+			report.Error = comp.err.Error()
+		}
+		summary.Components = append(summary.Components, report)
+	}
+	return summary
+}
+
+func (s *Shutdown) beginSummary(trigger, cause string, start time.Time) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	s.summary = ShutdownSummary{
+		Trigger: trigger,
+		Cause:   cause,
+		Start:   start,
+	}
+	if s.waitSeconds > 0 {
+		s.stopDeadline = start.Add(time.Duration(s.waitSeconds) * time.Second)
+	}
+
+	for _, comp := range s.order {
+		comp.state = ComponentPending
+	}
+}
+
+// deadline is zero when the shutdown waits permanently
+func (s *Shutdown) deadline() time.Time {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.stopDeadline
+}
+
+func (s *Shutdown) finishSummary(timedOut bool, duration time.Duration) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.summary.TimedOut = timedOut
+	s.summary.Duration = duration
+}
+
+func (s *Shutdown) setComponentState(comp *component, state ComponentState, err error, duration time.Duration) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	comp.state = state
+	comp.err = err
+	comp.duration = duration
+}
+
+// forceExitOnSignal
+// 當 shutdown 卡住時, 使用者再次發送 SIGINT/SIGTERM, 表示不想再等待, 立即結束程式
+func (s *Shutdown) forceExitOnSignal() {
+	select {
+	case sig := <-s.osSig:
+		s.logger.Error("recv os signal again, force exit",
+			slog.Any("signal", sig),
+			slog.Any("unfinished", s.Summary().Unfinished()),
+		)
+		s.exit(ShutdownForceExitCode)
+	case <-s.done:
+	}
+}
+
+// maxGoroutineDumpLog limits the dump which is logged directly,
+// it is used only when the dump file can't be written.
+const maxGoroutineDumpLog = 64 << 10
+
+// dumpGoroutines writes the stack of all goroutines to a temp file and returns its path,
+// so a huge dump does not break the log pipeline.
+func dumpGoroutines() slog.Attr {
+	stack := goroutineStack()
+
+	path, err := writeGoroutineDump(stack)
+	if err == nil {
+		return slog.String("goroutines_file", path)
+	}
+	return slog.Group("goroutines",
+		slog.String("dump_err", err.Error()),
+		slog.String("stack", truncateDump(stack, maxGoroutineDumpLog)),
+	)
+}
+
+func goroutineStack() []byte {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 16<<20 {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func writeGoroutineDump(stack []byte) (string, error) {
+	file, err := os.CreateTemp("", fmt.Sprintf("goroutines-%d-*.txt", os.Getpid()))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Write(stack)
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func truncateDump(stack []byte, limit int) string {
+	if len(stack) <= limit {
+		return string(stack)
+	}
+	return fmt.Sprintf("%s\n... truncated %d bytes", stack[:limit], len(stack)-limit)
+}
@@ -0,0 +1,233 @@
+package utility
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+func TestShutdown_topologicalOrder(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+
+	var mu sync.Mutex
+	var order []string
+	stop := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	add := func(name string, dependsOn ...string) error {
+		return shutdown.AddShutdownComponent(ShutdownComponent{Name: name, DependsOn: dependsOn, Stop: stop(name)})
+	}
+
+	assert.ErrorIs(t, add("http", "consumer"), ErrShutdownComponentNotFound)
+	require.NoError(t, add("tracer"))
+	require.NoError(t, add("mysql", "tracer"))
+	require.NoError(t, add("redis", "tracer"))
+	require.NoError(t, add("outbox", "mysql", "redis"))
+	require.NoError(t, add("consumer", "outbox"))
+	require.NoError(t, add("http", "consumer"))
+	shutdown.AddPriorityShutdownActionCtx(5, "event_log", stop("event_log"))
+	shutdown.AddPriorityShutdownActionCtx(4, "metric", stop("metric"))
+
+	assert.ErrorIs(t, add("self", "self"), ErrShutdownComponentCycle)
+	assert.ErrorIs(t, add("tracer", "http"), ErrShutdownComponentDuplicated)
+	require.NoError(t, add("gateway", "http"))
+	assert.ErrorIs(t, shutdown.AddPriorityShutdownComponent(6, ShutdownComponent{Name: "lb", DependsOn: []string{"metric"}}), ErrShutdownComponentCycle)
+
+	hang := make(chan struct{})
+	defer close(hang)
+	require.NoError(t, shutdown.AddShutdownComponent(ShutdownComponent{
+		Name:    "hang",
+		Timeout: 10 * time.Millisecond,
+		Stop: func(context.Context) error {
+			<-hang
+			return nil
+		},
+	}))
+
+	shutdown.Notify(nil)
+	shutdown.Serve()
+
+	index := func(name string) int { return slices.Index(order, name) }
+	assert.Len(t, order, 9)
+	assert.Less(t, index("gateway"), index("http"))
+	assert.Less(t, index("http"), index("consumer"))
+	assert.Less(t, index("consumer"), index("outbox"))
+	assert.Less(t, index("outbox"), index("mysql"))
+	assert.Less(t, index("outbox"), index("redis"))
+	assert.Less(t, index("mysql"), index("tracer"))
+	assert.Less(t, index("redis"), index("tracer"))
+	assert.Less(t, index("metric"), index("event_log"))
+}
+
+func TestShutdown_Summary(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 1, nil)
+
+	exitCode := make(chan int, 1)
+	shutdown.exit = func(code int) { exitCode <- code }
+
+	hang := make(chan struct{})
+	defer close(hang)
+	shutdown.AddPriorityShutdownAction(0, "http", func() error { return nil })
+	shutdown.AddPriorityShutdownAction(1, "consumer", func() error {
+		<-hang
+		return nil
+	})
+	shutdown.AddPriorityShutdownAction(2, "mysql", func() error { return nil })
+
+	shutdown.osSig <- syscall.SIGTERM
+	go func() {
+		for shutdown.Summary().Start.IsZero() {
+			time.Sleep(time.Millisecond)
+		}
+		shutdown.osSig <- syscall.SIGINT
+	}()
+	shutdown.Serve()
+
+	assert.Equal(t, ShutdownForceExitCode, <-exitCode)
+
+	summary := shutdown.Summary()
+	assert.Equal(t, "external", summary.Trigger)
+	assert.False(t, summary.TimedOut)
+	assert.Equal(t, []string{"consumer"}, summary.Unfinished())
+	require.Len(t, summary.Components, 3)
+	assert.Equal(t, ComponentStopped, summary.Components[0].State)
+	assert.Equal(t, ComponentTimeout, summary.Components[1].State)
+	assert.Equal(t, ComponentStopped, summary.Components[2].State)
+}
+
+func TestShutdown_AddShutdownActionCtx(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 1, nil)
+
+	budget := make(chan time.Duration, 1)
+	shutdown.AddPriorityShutdownActionCtx(0, "http", func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		budget <- time.Until(deadline)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	shutdown.AddPriorityShutdownActionCtx(1, "consumer", func(ctx context.Context) error { return nil })
+	shutdown.AddShutdownActionCtx("mysql", func(ctx context.Context) error { return nil })
+
+	shutdown.Notify(nil)
+	shutdown.Serve()
+
+	// http gets 1/3 of budget, the stuck drain does not starve mysql
+	summary := shutdown.Summary()
+	assert.False(t, summary.TimedOut)
+	assert.InDelta(t, time.Second/3, <-budget, float64(50*time.Millisecond))
+	assert.Equal(t, []string{"http"}, summary.Unfinished())
+	assert.Equal(t, ComponentTimeout, summary.Components[0].State)
+}
+
+func TestShutdownAdminHandler(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+	shutdown.AddShutdownAction("mysql", func() error { return nil })
+
+	var audit bytes.Buffer
+	guard := AdminGuard{Token: "secret"}
+	handler := ShutdownAdminHandler(guard, shutdown, wlog.NewEventLogger(&audit))
+
+	request := func(method string, target string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/admin/shutdown", "guess").Code)
+	assert.Contains(t, audit.String(), `"outcome":"denied"`)
+
+	recorder := request(http.MethodGet, "/admin/shutdown", "secret")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"name":"mysql","priority":2,"state":"running"`)
+
+	recorder = request(http.MethodPost, "/admin/shutdown?reason=blue-green", "secret")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	shutdown.Serve()
+
+	summary := shutdown.Summary()
+	assert.Equal(t, "admin", summary.Trigger)
+	assert.Equal(t, "blue-green", summary.Cause)
+	assert.Empty(t, summary.Unfinished())
+	assert.Contains(t, audit.String(), `"action":"shutdown.drain","resource":"shutdown","outcome":"success","detail":{"reason":"blue-green"}`)
+}
+
+func TestShutdown_SetDrainDelay(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil).SetDrainDelay(100 * time.Millisecond)
+
+	var start time.Time
+	var delay time.Duration
+	shutdown.AddShutdownAction("http", func() error {
+		delay = time.Since(start)
+		return nil
+	})
+
+	start = time.Now()
+	shutdown.Notify(nil)
+	shutdown.Serve()
+
+	assert.True(t, shutdown.Draining())
+	assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+}
+
+func TestDumpGoroutines(t *testing.T) {
+	attr := dumpGoroutines()
+	require.Equal(t, "goroutines_file", attr.Key)
+	defer os.Remove(attr.Value.String())
+
+	bData, err := os.ReadFile(attr.Value.String())
+	require.NoError(t, err)
+	assert.Contains(t, string(bData), "TestDumpGoroutines")
+
+	assert.Equal(t, "abc", truncateDump([]byte("abc"), 3))
+	assert.Equal(t, "ab\n... truncated 1 bytes", truncateDump([]byte("abc"), 2))
+}
+
+func TestShutdown_stopComponentTimeout(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), 0, nil)
+	require.NoError(t, shutdown.AddShutdownComponent(ShutdownComponent{
+		Name:    "http",
+		Timeout: 10 * time.Millisecond,
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	require.NoError(t, shutdown.AddShutdownComponent(ShutdownComponent{
+		Name:      "mysql",
+		DependsOn: []string{"http"},
+		Timeout:   10 * time.Millisecond,
+		Stop: func(ctx context.Context) error {
+			return StopWithCtx(ctx, func() error {
+				time.Sleep(time.Second)
+				return nil
+			})
+		},
+	}))
+
+	shutdown.Notify(nil)
+	shutdown.Serve()
+
+	summary := shutdown.Summary()
+	require.Len(t, summary.Components, 2)
+	assert.Equal(t, ComponentTimeout, summary.Components[0].State)
+	assert.Equal(t, ComponentTimeout, summary.Components[1].State)
+}
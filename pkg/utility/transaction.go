@@ -2,12 +2,26 @@ package utility
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// CtxWithGormTX attaches tx which is managed by caller,
+// Transaction.Begin detects it as the outermost transaction.
+//
+// The after-commit and after-rollback hooks of the tx are not executed,
+// use Transaction.Begin instead if hooks are required.
 func CtxWithGormTX(ctx context.Context, database *gorm.DB, tx *gorm.DB) context.Context {
-	return context.WithValue(ctx, database, tx)
+	scope := &gormTxScope{
+		database: database,
+		tx:       tx,
+		outer:    ctx,
+	}
+	return ctxWithGormTxScope(ctx, scope)
 }
 
 func CtxGetGormTX(ctx context.Context, database *gorm.DB) *gorm.DB {
@@ -20,6 +34,75 @@ func CtxGetGormTX(ctx context.Context, database *gorm.DB) *gorm.DB {
 
 //
 
+// TxPropagation 決定 Transaction.Begin 遇到 ctx 已經存在 tx 時的行為
+type TxPropagation int
+
+const (
+	// TxNested 若已存在 tx, 建立 SAVEPOINT, Rollback 只會回到 SAVEPOINT; 否則建立新的 tx
+	TxNested TxPropagation = iota
+
+	// TxRequired 若已存在 tx, 加入該 tx, Commit 由外層決定, Rollback 會使外層成為 rollback-only; 否則建立新的 tx
+	TxRequired
+
+	// TxRequiresNew 總是建立獨立的 tx, 使用另一條 connection, 與外層 tx 互不影響
+	TxRequiresNew
+
+	// TxNever 不使用 tx, 若已存在 tx, 回傳 ErrTxExisted
+	TxNever
+)
+
+func (p TxPropagation) String() string {
+	switch p {
+	case TxNested:
+		return "nested"
+	case TxRequired:
+		return "required"
+	case TxRequiresNew:
+		return "requires_new"
+	case TxNever:
+		return "never"
+	}
+	return fmt.Sprintf("TxPropagation(%d)", int(p))
+}
+
+var (
+	ErrTxExisted      = errors.New("transaction already exists")
+	ErrTxNotExist     = errors.New("transaction does not exist")
+	ErrTxRollbackOnly = errors.New("transaction is marked rollback-only")
+)
+
+//
+
+// AfterCommit registers the hook which is executed after the outermost transaction is committed,
+// e.g. publish domain events.
+//
+// If the nested transaction is rolled back to savepoint, its after-commit hooks are discarded.
+// If ctx has no transaction, the hook is executed immediately.
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	scope, ok := ctx.Value(gormTxScopeKey{}).(*gormTxScope)
+	if !ok || scope.never {
+		hook(ctx)
+		return
+	}
+	owner := scope.owner()
+	owner.afterCommit = append(owner.afterCommit, hook)
+}
+
+// AfterRollback registers the hook which is executed after the transaction is rolled back,
+// the nested transaction executes its hooks after rollback to savepoint.
+//
+// If ctx has no transaction, the hook is ignored.
+func AfterRollback(ctx context.Context, hook func(ctx context.Context)) {
+	scope, ok := ctx.Value(gormTxScopeKey{}).(*gormTxScope)
+	if !ok || scope.never {
+		return
+	}
+	owner := scope.owner()
+	owner.afterRollback = append(owner.afterRollback, hook)
+}
+
+//
+
 type EasyTransaction func(ctx context.Context, flow func(ctxTX context.Context) error) error
 
 func NonEasyTransaction() EasyTransaction {
@@ -31,12 +114,62 @@ func NonEasyTransaction() EasyTransaction {
 // NewGormEasyTransaction 把 tx *gorm.DB 放在 context.Context 進行參數傳遞,
 // 如此一來, 在應用服務層就可以隱藏 tx 物件, 只依賴抽象的 repository,
 // 而且在資料層也可以透過 CtxGetGormTX 取得 *gorm.DB
-func NewGormEasyTransaction(db *gorm.DB) EasyTransaction {
+//
+// 若 ctx 已經存在 tx, 預設使用 SAVEPOINT, 參考 TxNested
+//
+// WithTxRetry 會在 deadlock 等錯誤時重新執行整個 flow,
+// 只有建立最外層 tx 的 EasyTransaction 才會重試, 巢狀的 scope 直接回傳錯誤給外層
+func NewGormEasyTransaction(db *gorm.DB, opts ...GormTxOption) EasyTransaction {
+	transaction := newGormTX(db, opts)
 	return func(ctx context.Context, flow func(context.Context) error) error {
-		return db.Transaction(func(tx *gorm.DB) error {
-			return flow(CtxWithGormTX(ctx, db, tx))
-		})
+		for attempt := 1; ; attempt++ {
+			outermost, err := transaction.run(ctx, flow)
+			if err == nil {
+				return nil
+			}
+
+			if !outermost || transaction.maxAttempts <= 1 {
+				return err
+			}
+			reason, ok := transaction.retryable(err)
+			if !ok {
+				return err
+			}
+			if attempt >= transaction.maxAttempts {
+				transaction.metric.exhausted(reason)
+				return err
+			}
+			transaction.metric.retry(reason)
+
+			Err := sleepWithContext(ctx, transaction.retryDelay(attempt))
+			if Err != nil {
+				return errors.Join(err, Err)
+			}
+		}
+	}
+}
+
+func (g *gormTX) run(ctx context.Context, flow func(context.Context) error) (outermost bool, err error) {
+	ctxTX, err := g.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	scope, _ := ctxGetGormTxScope(ctxTX, g.db)
+	outermost = scope.parent == nil && !scope.never
+
+	finished := false
+	defer func() {
+		if !finished {
+			g.Rollback(ctxTX)
+		}
+	}()
+
+	err = flow(ctxTX)
+	if err != nil {
+		return outermost, err
 	}
+	finished = true
+	return outermost, g.Commit(ctxTX)
 }
 
 //
@@ -56,26 +189,208 @@ type Transaction interface {
 	Rollback(ctxTX context.Context) error
 }
 
-func NewGormTransaction(db *gorm.DB) Transaction {
-	return &gormTX{db: db}
+// NewGormTransaction 若 ctx 已經存在 tx, 預設使用 SAVEPOINT, 參考 TxNested
+//
+// WithTxRetry is ignored, since the flow between Begin and Commit can't be replayed.
+func NewGormTransaction(db *gorm.DB, opts ...GormTxOption) Transaction {
+	return newGormTX(db, opts)
+}
+
+func newGormTX(db *gorm.DB, opts []GormTxOption) *gormTX {
+	g := &gormTX{
+		db:          db,
+		propagation: TxNested,
+		retryable:   MySQLRetryReason,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
 }
 
 type gormTX struct {
-	db *gorm.DB
+	db          *gorm.DB
+	propagation TxPropagation
+	isolation   sql.IsolationLevel
+
+	maxAttempts int
+	backoff     time.Duration
+	retryable   func(err error) (reason string, ok bool)
+	metric      *TxO11YMetric
 }
 
 func (g *gormTX) Begin(ctx context.Context) (ctxTX context.Context, err error) {
-	tx := g.db.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
+	parent, exist := ctxGetGormTxScope(ctx, g.db)
+	if exist && parent.never {
+		exist = false
+	}
+
+	scope := &gormTxScope{
+		database: g.db,
+		outer:    ctx,
+	}
+
+	switch {
+	case g.propagation == TxNever:
+		if exist {
+			return nil, fmt.Errorf("propagation %v: %w", g.propagation, ErrTxExisted)
+		}
+		scope.tx = g.db
+		scope.never = true
+
+	case exist && g.propagation == TxRequired:
+		scope.tx = parent.tx
+		scope.parent = parent
+		scope.joined = true
+
+	case exist && g.propagation == TxNested:
+		scope.tx = parent.tx
+		scope.parent = parent
+		scope.depth = parent.depth + 1
+		root := parent.root()
+		root.savepointSeq++
+		scope.savepoint = fmt.Sprintf("sp%d", root.savepointSeq)
+		err = scope.tx.SavePoint(scope.savepoint).Error
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		var txOpts []*sql.TxOptions
+		if g.isolation != sql.LevelDefault {
+			txOpts = append(txOpts, &sql.TxOptions{Isolation: g.isolation})
+		}
+		tx := g.db.Begin(txOpts...)
+		if tx.Error != nil {
+			return nil, tx.Error
+		}
+		scope.tx = tx
 	}
-	return CtxWithGormTX(ctx, g.db, tx), nil
+
+	return ctxWithGormTxScope(ctx, scope), nil
 }
 
 func (g *gormTX) Commit(ctxTX context.Context) error {
-	return CtxGetGormTX(ctxTX, g.db).Commit().Error
+	scope, ok := ctxGetGormTxScope(ctxTX, g.db)
+	if !ok {
+		return ErrTxNotExist
+	}
+
+	switch {
+	case scope.never || scope.joined:
+		return nil
+
+	case scope.rollbackOnly:
+		// the joined scope has been rolled back, the owner can't commit
+		err := scope.rollback()
+		return errors.Join(ErrTxRollbackOnly, err)
+
+	case scope.savepoint != "":
+		err := scope.tx.Exec("RELEASE SAVEPOINT " + scope.savepoint).Error
+		if err != nil {
+			return errors.Join(err, scope.rollback())
+		}
+
+		// the hooks of savepoint are decided by the parent.
+		owner := scope.parent.owner()
+		owner.afterCommit = append(owner.afterCommit, scope.afterCommit...)
+		owner.afterRollback = append(owner.afterRollback, scope.afterRollback...)
+		return nil
+	}
+
+	err := scope.tx.Commit().Error
+	if err != nil {
+		scope.runHooks(scope.afterRollback)
+		return err
+	}
+	scope.runHooks(scope.afterCommit)
+	return nil
 }
 
+// Rollback of TxRequired scope marks the owner as rollback-only,
+// then Commit of the owner rolls back and returns ErrTxRollbackOnly.
 func (g *gormTX) Rollback(ctxTX context.Context) error {
-	return CtxGetGormTX(ctxTX, g.db).Rollback().Error
+	scope, ok := ctxGetGormTxScope(ctxTX, g.db)
+	if !ok {
+		return ErrTxNotExist
+	}
+
+	switch {
+	case scope.never:
+		return nil
+
+	case scope.joined:
+		scope.owner().rollbackOnly = true
+		return nil
+	}
+
+	return scope.rollback()
+}
+
+//
+
+type gormTxScopeKey struct {
+	database *gorm.DB
+}
+
+// gormTxScope is stored twice in the context,
+// gormTxScopeKey{database} is used by Transaction, gormTxScopeKey{} is used by AfterCommit.
+type gormTxScope struct {
+	database *gorm.DB
+	tx       *gorm.DB
+	outer    context.Context // the context before Begin, it is used by hooks
+
+	parent       *gormTxScope
+	depth        int
+	savepoint    string // nested
+	savepointSeq int    // root, the savepoint name is unique in the tx
+	joined       bool   // required
+	never        bool   // never
+	rollbackOnly bool   // the joined scope is rolled back
+
+	afterCommit   []func(ctx context.Context)
+	afterRollback []func(ctx context.Context)
+}
+
+// owner is the scope which decides commit or rollback
+func (s *gormTxScope) owner() *gormTxScope {
+	for s.joined {
+		s = s.parent
+	}
+	return s
+}
+
+func (s *gormTxScope) root() *gormTxScope {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+func (s *gormTxScope) rollback() error {
+	var err error
+	if s.savepoint != "" {
+		err = s.tx.RollbackTo(s.savepoint).Error
+	} else {
+		err = s.tx.Rollback().Error
+	}
+	s.runHooks(s.afterRollback)
+	return err
+}
+
+func (s *gormTxScope) runHooks(hooks []func(ctx context.Context)) {
+	for _, hook := range hooks {
+		hook(s.outer)
+	}
+}
+
+func ctxWithGormTxScope(ctx context.Context, scope *gormTxScope) context.Context {
+	ctx = context.WithValue(ctx, scope.database, scope.tx)
+	ctx = context.WithValue(ctx, gormTxScopeKey{database: scope.database}, scope)
+	return context.WithValue(ctx, gormTxScopeKey{}, scope)
+}
+
+func ctxGetGormTxScope(ctx context.Context, database *gorm.DB) (*gormTxScope, bool) {
+	scope, ok := ctx.Value(gormTxScopeKey{database: database}).(*gormTxScope)
+	return scope, ok
 }
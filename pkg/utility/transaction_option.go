@@ -0,0 +1,142 @@
+package utility
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type GormTxOption func(g *gormTX)
+
+// WithTxPropagation default is TxNested
+func WithTxPropagation(propagation TxPropagation) GormTxOption {
+	return func(g *gormTX) {
+		g.propagation = propagation
+	}
+}
+
+// WithTxIsolation is applied when the outermost tx begins,
+// the nested scope uses the isolation of outer tx.
+func WithTxIsolation(isolation sql.IsolationLevel) GormTxOption {
+	return func(g *gormTX) {
+		g.isolation = isolation
+	}
+}
+
+// WithTxRetry re-executes the whole flow when MySQLRetryReason matches,
+// the backoff is doubled after each attempt, e.g. 50ms, 100ms, 200ms, and capped by TxMaxBackoff.
+// The actual delay is a random value between half and full backoff,
+// so the deadlocked transactions don't retry at the same time.
+//
+// The flow must be idempotent except for database operations,
+// side effects should be registered by AfterCommit.
+func WithTxRetry(maxAttempts int, backoff time.Duration) GormTxOption {
+	return func(g *gormTX) {
+		g.maxAttempts = maxAttempts
+		g.backoff = backoff
+	}
+}
+
+func WithTxMetric(metric *TxO11YMetric) GormTxOption {
+	return func(g *gormTX) {
+		g.metric = metric
+	}
+}
+
+//
+
+const (
+	mysqlDeadlock        = 1213
+	mysqlLockWaitTimeout = 1205
+)
+
+// MySQLRetryReason
+//
+// 1213 deadlock: MySQL rolls back the whole transaction
+// 1205 lock wait timeout: MySQL rolls back the statement by default, but the flow is retried as a whole
+//
+// The repository must wrap the driver error by %w, otherwise it can't be detected.
+func MySQLRetryReason(err error) (reason string, ok bool) {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return "", false
+	}
+	switch myErr.Number {
+	case mysqlDeadlock:
+		return "deadlock", true
+	case mysqlLockWaitTimeout:
+		return "lock_wait_timeout", true
+	}
+	return "", false
+}
+
+// TxMaxBackoff is the upper bound of WithTxRetry backoff
+const TxMaxBackoff = 2 * time.Second
+
+func (g *gormTX) retryDelay(attempt int) time.Duration {
+	delay := g.backoff
+	for i := 1; i < attempt && delay < TxMaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, TxMaxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+//
+
+func NewTxO11YMetric(svcName string) *TxO11YMetric {
+	return &TxO11YMetric{
+		RetriesTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "db",
+			Name:      "transaction_retries_total",
+			Help:      "Total number of transaction retries",
+		}, []string{"reason"}),
+
+		RetriesExhausted: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: svcName,
+			Subsystem: "db",
+			Name:      "transaction_retries_exhausted_total",
+			Help:      "Total number of transactions which failed after all attempts",
+		}, []string{"reason"}),
+	}
+}
+
+type TxO11YMetric struct {
+	RetriesTotal     *prometheus.CounterVec
+	RetriesExhausted *prometheus.CounterVec
+}
+
+func (m *TxO11YMetric) retry(reason string) {
+	if m == nil {
+		return
+	}
+	m.RetriesTotal.WithLabelValues(reason).Inc()
+}
+
+func (m *TxO11YMetric) exhausted(reason string) {
+	if m == nil {
+		return
+	}
+	m.RetriesExhausted.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,218 @@
+package utility
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestGormTransaction_nested(t *testing.T) {
+	db, statements := newRecordGorm(t)
+	transaction := NewGormTransaction(db)
+	easyTransaction := NewGormEasyTransaction(db)
+
+	var events []string
+	ctxTX, err := transaction.Begin(context.Background())
+	require.NoError(t, err)
+	AfterCommit(ctxTX, func(context.Context) { events = append(events, "outer committed") })
+
+	errFlow := errors.New("flow failed")
+	err = easyTransaction(ctxTX, func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { events = append(events, "nested1 committed") })
+		AfterRollback(ctx, func(context.Context) { events = append(events, "nested1 rolled back") })
+		return errFlow
+	})
+	assert.ErrorIs(t, err, errFlow)
+
+	err = easyTransaction(ctxTX, func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { events = append(events, "nested2 committed") })
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nested1 rolled back"}, events)
+
+	require.NoError(t, transaction.Commit(ctxTX))
+	assert.Equal(t, []string{"nested1 rolled back", "outer committed", "nested2 committed"}, events)
+	assert.Equal(t, []string{"BEGIN", "SAVEPOINT sp1", "ROLLBACK TO SAVEPOINT sp1", "SAVEPOINT sp2", "RELEASE SAVEPOINT sp2", "COMMIT"}, *statements)
+}
+
+func TestGormTransaction_propagation(t *testing.T) {
+	db, statements := newRecordGorm(t)
+
+	ctxTX, err := NewGormTransaction(db).Begin(context.Background())
+	require.NoError(t, err)
+
+	// required joins the outer tx, the outer tx decides commit or rollback
+	committed := false
+	err = NewGormEasyTransaction(db, WithTxPropagation(TxRequired))(ctxTX, func(ctx context.Context) error {
+		assert.Same(t, CtxGetGormTX(ctxTX, db), CtxGetGormTX(ctx, db))
+		AfterCommit(ctx, func(context.Context) { committed = true })
+		return nil
+	})
+	require.NoError(t, err)
+	assert.False(t, committed)
+
+	// requires-new is independent of the outer tx
+	err = NewGormEasyTransaction(db, WithTxPropagation(TxRequiresNew))(ctxTX, func(ctx context.Context) error {
+		assert.NotSame(t, CtxGetGormTX(ctxTX, db), CtxGetGormTX(ctx, db))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = NewGormEasyTransaction(db, WithTxPropagation(TxNever))(ctxTX, func(ctx context.Context) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrTxExisted)
+
+	require.NoError(t, NewGormTransaction(db).Rollback(ctxTX))
+	assert.False(t, committed)
+	assert.Equal(t, []string{"BEGIN", "BEGIN", "COMMIT", "ROLLBACK"}, *statements)
+}
+
+func TestGormTransaction_rollbackOnly(t *testing.T) {
+	db, statements := newRecordGorm(t)
+	transaction := NewGormTransaction(db)
+	required := NewGormEasyTransaction(db, WithTxPropagation(TxRequired))
+
+	ctxTX, err := transaction.Begin(context.Background())
+	require.NoError(t, err)
+
+	rolledBack := false
+	AfterRollback(ctxTX, func(context.Context) { rolledBack = true })
+
+	// the caller swallows the error of joined scope, but the outer tx can't commit
+	errFlow := errors.New("flow failed")
+	err = required(ctxTX, func(ctx context.Context) error { return errFlow })
+	assert.ErrorIs(t, err, errFlow)
+
+	err = transaction.Commit(ctxTX)
+	assert.ErrorIs(t, err, ErrTxRollbackOnly)
+	assert.True(t, rolledBack)
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, *statements)
+
+	// the nested savepoint is the owner of joined scope
+	*statements = nil
+	err = NewGormEasyTransaction(db)(context.Background(), func(ctx context.Context) error {
+		err := NewGormEasyTransaction(db)(ctx, func(ctx context.Context) error {
+			required(ctx, func(ctx context.Context) error { return errFlow })
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrTxRollbackOnly)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "SAVEPOINT sp1", "ROLLBACK TO SAVEPOINT sp1", "COMMIT"}, *statements)
+}
+
+func TestGormEasyTransaction_retry(t *testing.T) {
+	db, statements := newRecordGorm(t)
+	easyTransaction := NewGormEasyTransaction(db, WithTxRetry(3, time.Millisecond))
+
+	errDeadlock := &mysqlDriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	attempts := 0
+	err := easyTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("lock user: %w", errDeadlock)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}, *statements)
+
+	// the nested scope returns error to the outermost tx, it is not retried alone
+	*statements = nil
+	attempts = 0
+	err = easyTransaction(context.Background(), func(ctx context.Context) error {
+		return easyTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			return errDeadlock
+		})
+	})
+	assert.ErrorIs(t, err, errDeadlock)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 3, strings.Count(strings.Join(*statements, ","), "BEGIN"))
+}
+
+// newRecordGorm records the transaction statements without database
+func newRecordGorm(t *testing.T) (*gorm.DB, *[]string) {
+	statements := new([]string)
+	sqlDB := sql.OpenDB(&recordConnector{statements: statements})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+	return db, statements
+}
+
+type recordConnector struct {
+	statements *[]string
+}
+
+func (r *recordConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordConn{statements: r.statements}, nil
+}
+
+func (r *recordConnector) Driver() driver.Driver { return nil }
+
+type recordConn struct {
+	statements *[]string
+}
+
+func (r *recordConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *recordConn) Close() error { return nil }
+
+func (r *recordConn) Begin() (driver.Tx, error) {
+	*r.statements = append(*r.statements, "BEGIN")
+	return r, nil
+}
+
+func (r *recordConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	*r.statements = append(*r.statements, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (r *recordConn) Commit() error {
+	*r.statements = append(*r.statements, "COMMIT")
+	return nil
+}
+
+func (r *recordConn) Rollback() error {
+	*r.statements = append(*r.statements, "ROLLBACK")
+	return nil
+}
+
+func TestGormTX_retryDelay(t *testing.T) {
+	transaction := newGormTX(nil, []GormTxOption{WithTxRetry(100, 50*time.Millisecond)})
+
+	for attempt, backoff := range map[int]time.Duration{
+		1:  50 * time.Millisecond,
+		3:  200 * time.Millisecond,
+		99: TxMaxBackoff, // the shift would overflow without cap
+	} {
+		for range 10 {
+			delay := transaction.retryDelay(attempt)
+			assert.GreaterOrEqual(t, delay, backoff/2, attempt)
+			assert.LessOrEqual(t, delay, backoff, attempt)
+		}
+	}
+}
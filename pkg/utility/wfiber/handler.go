@@ -9,9 +9,20 @@ import (
 	"github.com/KScaesar/go-layout/pkg/utility/wlog"
 )
 
-func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger) fiber.Handler {
+// ChangeLoggerLevel is a security action, the update and the failed challenge are recorded by event logger.
+func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger, event *wlog.EventLogger) fiber.Handler {
+	const action = "logger.level.update"
+	const resource = "logger"
+
 	return func(c *fiber.Ctx) error {
 		if !hack.Challenge(c.Query("hack")) {
+			event.Emit(c.UserContext(), wlog.Event{
+				Actor:    c.IP(),
+				Action:   action,
+				Resource: resource,
+				Outcome:  wlog.OutcomeDenied,
+				Reason:   "hack challenge failed",
+			})
 			return nil
 		}
 
@@ -33,6 +44,12 @@ func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger) fiber.Handler {
 		lvl := wlogger.Level().String()
 		if update {
 			logger.Info("update logger level", slog.String("level", lvl))
+			event.Emit(c.UserContext(), wlog.Event{
+				Actor:    c.IP(),
+				Action:   action,
+				Resource: resource,
+				Detail:   fiber.Map{"level": lvl},
+			})
 		} else {
 			logger.Info("get logger level", slog.String("level", lvl))
 		}
@@ -40,3 +57,45 @@ func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger) fiber.Handler {
 		return c.JSON(fiber.Map{"level": lvl})
 	}
 }
+
+// ReloadLogger rebuilds the logger by wlog.ReloadRequest without restart,
+// it is a security action which is recorded by event logger.
+func ReloadLogger(hack utility.Hack, wlogger *wlog.Logger, event *wlog.EventLogger) fiber.Handler {
+	const action = "logger.config.reload"
+	const resource = "logger"
+
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		if !hack.Challenge(c.Query("hack")) {
+			event.Emit(ctx, wlog.Event{
+				Actor:    c.IP(),
+				Action:   action,
+				Resource: resource,
+				Outcome:  wlog.OutcomeDenied,
+				Reason:   "hack challenge failed",
+			})
+			return nil
+		}
+
+		var req wlog.ReloadRequest
+		err := c.BodyParser(&req)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		err = wlogger.ReloadByRequest(&req)
+		event.EmitResult(ctx, wlog.Event{
+			Actor:    c.IP(),
+			Action:   action,
+			Resource: resource,
+			Detail:   req,
+		}, err)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		wlogger.SetStdDefaultLevel()
+
+		wlogger.CtxGetLogger(ctx).Info("reload logger config", slog.String("file", wlogger.Filename()))
+		return c.JSON(fiber.Map{"level": wlogger.Level().String(), "file": wlogger.Filename()})
+	}
+}
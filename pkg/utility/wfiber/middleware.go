@@ -12,7 +12,6 @@ import (
 	"github.com/samber/slog-fiber"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
-	"gorm.io/gorm"
 
 	"github.com/KScaesar/go-layout/pkg/utility"
 	"github.com/KScaesar/go-layout/pkg/utility/wlog"
@@ -114,7 +113,8 @@ func (m *O11YMetric) Middleware(c *fiber.Ctx) error {
 //
 // handler1: logs HTTP messages
 //
-// handler2: adds a logger with req_id to the standard context, allowing subsequent functions to use logger from ctx
+// handler2: adds req_id and route to the standard context by wlog.CtxWithAttrs,
+// every record logged with ctx carries them, trace_id and span_id are added by wlog.ContextHandler
 func O11YLogger(debug bool, enableTrace bool, wlogger *wlog.Logger) (fiber.Handler, fiber.Handler) {
 	var config slogfiber.Config
 	config.WithRequestID = true
@@ -139,30 +139,17 @@ func O11YLogger(debug bool, enableTrace bool, wlogger *wlog.Logger) (fiber.Handl
 	handler1 := slogfiber.NewWithConfig(wlogger.Slog(), config)
 
 	handler2 := func(c *fiber.Ctx) error {
-		ctx := c.UserContext()
-
 		reqId := string(c.Response().Header.Peek(slogfiber.RequestIDHeaderKey))
 		requestAttributes := []slog.Attr{
 			slog.String("method", c.Method()),
 			slog.String("route", c.Route().Path),
 		}
-		logger := wlogger.Slog().With(
+		ctx := wlog.CtxWithAttrs(c.UserContext(),
 			slog.Any("request", slog.GroupValue(requestAttributes...)),
 			slog.String(slogfiber.RequestIDKey, reqId),
 		)
 
-		if enableTrace {
-			span := trace.SpanFromContext(ctx)
-			traceId := span.SpanContext().TraceID().String()
-			spanId := span.SpanContext().SpanID().String()
-
-			logger = logger.With(
-				slog.String(slogfiber.TraceIDKey, traceId),
-				slog.String(slogfiber.SpanIDKey, spanId),
-			)
-		}
-
-		c.SetUserContext(wlogger.CtxWithLogger(ctx, logger))
+		c.SetUserContext(ctx)
 
 		return c.Next()
 	}
@@ -170,53 +157,26 @@ func O11YLogger(debug bool, enableTrace bool, wlogger *wlog.Logger) (fiber.Handl
 	return handler1, handler2
 }
 
-// GormTX
+// Authenticate verifies the bearer token, and then puts utility.Principal into the user context,
+// the handler gets it by utility.CtxGetPrincipal.
 //
-// 若 skip == nil, 所有條件都會使用 tx
-// 若 skip != nil, 滿足條件的, 將不會啟動 tx
-func GormTX(db *gorm.DB, skip func(ctx *fiber.Ctx) bool, wlogger *wlog.Logger) fiber.Handler {
-	skipMethods := map[string]bool{
-		http.MethodHead:    true,
-		http.MethodConnect: true,
-		http.MethodOptions: true,
-		http.MethodTrace:   true,
-	}
-
+// unauthorized is used when the token is missing, e.g. pkg.ErrUnauthorized
+func Authenticate(verify utility.TokenVerifier, unauthorized error) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		canSkip := db == nil ||
-			skipMethods[c.Method()] ||
-			(skip != nil && skip(c))
-
-		if canSkip {
-			return c.Next()
+		token, ok := utility.ParseBearerToken(c.Get(fiber.HeaderAuthorization))
+		if !ok {
+			return unauthorized
 		}
 
-		stdCtx := c.UserContext()
-		logger := wlogger.CtxGetLogger(stdCtx)
-
-		tx := db.Begin()
-		err := tx.Error
+		ctx := c.UserContext()
+		principal, err := verify(ctx, token)
 		if err != nil {
-			logger.Error("gorm tx begin failed", "err", err)
 			return err
 		}
 
-		c.SetUserContext(utility.CtxWithGormTX(stdCtx, db, tx))
-
-		err = c.Next()
-		if err != nil || c.Response().StatusCode() >= http.StatusBadRequest {
-			Err := tx.Rollback().Error
-			if Err != nil {
-				logger.Error("gorm tx rollback failed", "err", Err)
-			}
-			return err
-		}
-
-		Err := tx.Commit().Error
-		if Err != nil {
-			logger.Error("gorm tx commit failed", "err", Err)
-			return Err
-		}
-		return nil
+		ctx = utility.CtxWithPrincipal(ctx, principal)
+		ctx = wlog.CtxWithAttrs(ctx, slog.String("user_id", principal.UserId))
+		c.SetUserContext(ctx)
+		return c.Next()
 	}
 }
@@ -0,0 +1,84 @@
+package wfiber
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KScaesar/go-layout/pkg/utility"
+)
+
+func TestAuthenticate(t *testing.T) {
+	errUnauthorized := errors.New("unauthorized")
+	errRevoked := errors.New("token has been revoked")
+	verify := func(ctx context.Context, token string) (utility.Principal, error) {
+		if token == "revoked" {
+			return utility.Principal{}, errRevoked
+		}
+		return utility.Principal{UserId: token}, nil
+	}
+
+	router := fiber.New()
+	errorHandler := func(c *fiber.Ctx, err error) error {
+		return c.Status(fiber.StatusUnauthorized).SendString(err.Error())
+	}
+	NewRoutes(router, errorHandler).
+		Group("", Authenticate(verify, errUnauthorized)).
+		Get("/me", func(c *fiber.Ctx) error {
+			principal, _ := utility.CtxGetPrincipal(c.UserContext())
+			return c.SendString(principal.UserId)
+		})
+
+	tests := []struct {
+		name          string
+		authorization string
+		code          int
+		body          string
+	}{
+		{
+			name:          "valid token",
+			authorization: "Bearer u1",
+			code:          http.StatusOK,
+			body:          "u1",
+		},
+		{
+			name: "missing token",
+			code: http.StatusUnauthorized,
+			body: "unauthorized",
+		},
+		{
+			name:          "not bearer",
+			authorization: "Basic dTE6cGFzc3dvcmQ=",
+			code:          http.StatusUnauthorized,
+			body:          "unauthorized",
+		},
+		{
+			name:          "verify failed",
+			authorization: "bearer revoked",
+			code:          http.StatusUnauthorized,
+			body:          "token has been revoked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+			resp, err := router.Test(req)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.code, resp.StatusCode)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
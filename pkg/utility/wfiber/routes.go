@@ -0,0 +1,86 @@
+package wfiber
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewRoutes
+// 由於 middleware 無法取得 handler route, ref: https://github.com/gofiber/fiber/issues/3138
+// 需要 route 的 middleware 必須與 handler 一起註冊, Routes 會在每個 route 上套用相同的 middleware chain.
+//
+// 最終每個 route 的 handler 順序為:
+//
+//	middlewares... -> HandleError(errorHandler) -> handler
+//
+// HandleError 在 handler 之後立刻轉換 error, 所以外層的 metric, logger, tx 都能取得正確的 http code,
+// handler 只需要 return error, 不必手動呼叫 errorHandler.
+func NewRoutes(router fiber.Router, errorHandler fiber.ErrorHandler, middlewares ...fiber.Handler) *Routes {
+	chain := make([]fiber.Handler, 0, len(middlewares)+1)
+	chain = append(chain, middlewares...)
+	chain = append(chain, HandleError(errorHandler))
+	return &Routes{
+		router: router,
+		chain:  chain,
+	}
+}
+
+type Routes struct {
+	router fiber.Router
+	chain  []fiber.Handler
+}
+
+func (r *Routes) Get(path string, handler fiber.Handler) *Routes {
+	return r.Add(http.MethodGet, path, handler)
+}
+
+func (r *Routes) Post(path string, handler fiber.Handler) *Routes {
+	return r.Add(http.MethodPost, path, handler)
+}
+
+func (r *Routes) Put(path string, handler fiber.Handler) *Routes {
+	return r.Add(http.MethodPut, path, handler)
+}
+
+func (r *Routes) Patch(path string, handler fiber.Handler) *Routes {
+	return r.Add(http.MethodPatch, path, handler)
+}
+
+func (r *Routes) Delete(path string, handler fiber.Handler) *Routes {
+	return r.Add(http.MethodDelete, path, handler)
+}
+
+func (r *Routes) Add(method string, path string, handler fiber.Handler) *Routes {
+	handlers := make([]fiber.Handler, 0, len(r.chain)+1)
+	handlers = append(handlers, r.chain...)
+	handlers = append(handlers, handler)
+	r.router.Add(method, path, handlers...)
+	return r
+}
+
+// Group creates the sub routes with the same middleware chain,
+// the middlewares are appended after HandleError, so their errors are converted too, e.g. Authenticate
+//
+//	middlewares... -> HandleError(errorHandler) -> group middlewares... -> handler
+func (r *Routes) Group(prefix string, middlewares ...fiber.Handler) *Routes {
+	chain := make([]fiber.Handler, 0, len(r.chain)+len(middlewares))
+	chain = append(chain, r.chain...)
+	chain = append(chain, middlewares...)
+	return &Routes{
+		router: r.router.Group(prefix),
+		chain:  chain,
+	}
+}
+
+// HandleError converts the error returned by next handler into response,
+// the converted error is not returned, so config.ErrorHandler is not called again.
+func HandleError(errorHandler fiber.ErrorHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if err == nil {
+			return nil
+		}
+		return errorHandler(c, err)
+	}
+}
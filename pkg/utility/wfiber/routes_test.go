@@ -0,0 +1,126 @@
+package wfiber
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutes(t *testing.T) {
+	var events []string
+	configErrorHandlerQty := 0
+	router := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			configErrorHandlerQty++
+			return fiber.DefaultErrorHandler(c, err)
+		},
+	})
+
+	errorHandler := func(c *fiber.Ctx, err error) error {
+		events = append(events, "error handler: "+err.Error())
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	middleware := func(name string) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			events = append(events, name+" start")
+			err := c.Next()
+			events = append(events, name+" finish: "+strconv.Itoa(c.Response().StatusCode()))
+			return err
+		}
+	}
+	handler := func(err error) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			events = append(events, "handler "+c.Route().Path)
+			if err != nil {
+				return err
+			}
+			return c.SendString("ok")
+		}
+	}
+	deny := func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderAuthorization) == "" {
+			return errors.New("unauthorized")
+		}
+		return c.Next()
+	}
+
+	routes := NewRoutes(router, errorHandler, middleware("metric"), middleware("logger"))
+	same := routes.
+		Get("/ok", handler(nil)).
+		Post("/fail", handler(errors.New("invalid param")))
+	assert.Same(t, routes, same)
+
+	v1 := routes.Group("/api/v1", deny)
+	v1.Get("/users", handler(nil))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		header string
+		code   int
+		body   string
+		events []string
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			target: "/ok",
+			code:   http.StatusOK,
+			body:   "ok",
+			events: []string{"metric start", "logger start", "handler /ok", "logger finish: 200", "metric finish: 200"},
+		},
+		{
+			name:   "handler error is converted before outer middlewares finish",
+			method: http.MethodPost,
+			target: "/fail",
+			code:   http.StatusBadRequest,
+			body:   "invalid param",
+			events: []string{"metric start", "logger start", "handler /fail", "error handler: invalid param", "logger finish: 400", "metric finish: 400"},
+		},
+		{
+			name:   "group middleware error is converted",
+			method: http.MethodGet,
+			target: "/api/v1/users",
+			code:   http.StatusBadRequest,
+			body:   "unauthorized",
+			events: []string{"metric start", "logger start", "error handler: unauthorized", "logger finish: 400", "metric finish: 400"},
+		},
+		{
+			name:   "group route",
+			method: http.MethodGet,
+			target: "/api/v1/users",
+			header: "Bearer token",
+			code:   http.StatusOK,
+			body:   "ok",
+			events: []string{"metric start", "logger start", "handler /api/v1/users", "logger finish: 200", "metric finish: 200"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.header)
+			}
+			resp, err := router.Test(req)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.code, resp.StatusCode)
+			assert.Equal(t, tt.body, string(body))
+			assert.Equal(t, tt.events, events)
+		})
+	}
+
+	// the converted error is not returned, config.ErrorHandler only handles fiber errors
+	assert.Zero(t, configErrorHandlerQty)
+}
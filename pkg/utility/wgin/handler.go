@@ -10,9 +10,20 @@ import (
 	"github.com/KScaesar/go-layout/pkg/utility/wlog"
 )
 
-func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger) gin.HandlerFunc {
+// ChangeLoggerLevel is a security action, the update and the failed challenge are recorded by event logger.
+func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger, event *wlog.EventLogger) gin.HandlerFunc {
+	const action = "logger.level.update"
+	const resource = "logger"
+
 	return func(c *gin.Context) {
 		if !hack.Challenge(c.Query("hack")) {
+			event.Emit(c.Request.Context(), wlog.Event{
+				Actor:    c.ClientIP(),
+				Action:   action,
+				Resource: resource,
+				Outcome:  wlog.OutcomeDenied,
+				Reason:   "hack challenge failed",
+			})
 			return
 		}
 
@@ -34,6 +45,12 @@ func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger) gin.HandlerFunc
 		lvl := wlogger.Level().String()
 		if update {
 			logger.Info("update logger level", slog.String("level", lvl))
+			event.Emit(c.Request.Context(), wlog.Event{
+				Actor:    c.ClientIP(),
+				Action:   action,
+				Resource: resource,
+				Detail:   gin.H{"level": lvl},
+			})
 		} else {
 			logger.Info("get logger level", slog.String("level", lvl))
 		}
@@ -41,3 +58,47 @@ func ChangeLoggerLevel(hack utility.Hack, wlogger *wlog.Logger) gin.HandlerFunc
 		c.JSON(http.StatusOK, gin.H{"level": lvl})
 	}
 }
+
+// ReloadLogger rebuilds the logger by wlog.ReloadRequest without restart,
+// it is a security action which is recorded by event logger.
+func ReloadLogger(hack utility.Hack, wlogger *wlog.Logger, event *wlog.EventLogger) gin.HandlerFunc {
+	const action = "logger.config.reload"
+	const resource = "logger"
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if !hack.Challenge(c.Query("hack")) {
+			event.Emit(ctx, wlog.Event{
+				Actor:    c.ClientIP(),
+				Action:   action,
+				Resource: resource,
+				Outcome:  wlog.OutcomeDenied,
+				Reason:   "hack challenge failed",
+			})
+			return
+		}
+
+		var req wlog.ReloadRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err = wlogger.ReloadByRequest(&req)
+		event.EmitResult(ctx, wlog.Event{
+			Actor:    c.ClientIP(),
+			Action:   action,
+			Resource: resource,
+			Detail:   req,
+		}, err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		wlogger.SetStdDefaultLevel()
+
+		wlogger.CtxGetLogger(ctx).Info("reload logger config", slog.String("file", wlogger.Filename()))
+		c.JSON(http.StatusOK, gin.H{"level": wlogger.Level().String(), "file": wlogger.Filename()})
+	}
+}
@@ -1,8 +1,10 @@
 package wgin
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"strconv"
 	"time"
 
@@ -12,7 +14,6 @@ import (
 	"github.com/samber/slog-gin"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
-	"gorm.io/gorm"
 
 	"github.com/KScaesar/go-layout/pkg/utility"
 	"github.com/KScaesar/go-layout/pkg/utility/wlog"
@@ -53,6 +54,14 @@ func O11YMetric(svcName string) gin.HandlerFunc {
 		Help:      "Total number of HTTP errors",
 	}, []string{"method", "route", "code"})
 
+	// the separate metric keeps the existing series of HttpErrorsTotal
+	HttpErrorCodesTotal := promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: svcName,
+		Subsystem: "http",
+		Name:      "requests_total_error_codes",
+		Help:      "Total number of HTTP errors by error code, see HandleError",
+	}, []string{"method", "route", "err_code"})
+
 	HttpRequestsInflight := promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: svcName,
 		Subsystem: "http",
@@ -91,6 +100,8 @@ func O11YMetric(svcName string) gin.HandlerFunc {
 		code := strconv.Itoa(c.Writer.Status())
 		if code[0] == '4' || code[0] == '5' {
 			HttpErrorsTotal.WithLabelValues(method, route, code).Inc()
+			errCode := strconv.Itoa(GetErrorCode(c))
+			HttpErrorCodesTotal.WithLabelValues(method, route, errCode).Inc()
 		}
 
 		// metric1
@@ -108,6 +119,12 @@ func O11YMetric(svcName string) gin.HandlerFunc {
 	}
 }
 
+// O11YLogger
+//
+// h1: logs HTTP messages
+//
+// h2: adds req_id and route to the standard context by wlog.CtxWithAttrs,
+// every record logged with ctx carries them, trace_id and span_id are added by wlog.ContextHandler
 func O11YLogger(debug bool, enableTrace bool, Logger *wlog.Logger) (gin.HandlerFunc, gin.HandlerFunc) {
 	var config sloggin.Config
 	config.WithRequestID = true
@@ -132,85 +149,113 @@ func O11YLogger(debug bool, enableTrace bool, Logger *wlog.Logger) (gin.HandlerF
 	h1 := sloggin.NewWithConfig(Logger.Slog(), config)
 
 	h2 := func(c *gin.Context) {
-		ctx := c.Request.Context()
-
 		reqId := c.Writer.Header().Get(sloggin.RequestIDHeaderKey)
 		requestAttributes := []slog.Attr{
 			slog.String("method", c.Request.Method),
 			slog.String("route", c.FullPath()),
 		}
-		logger := Logger.Slog().With(
+		ctx := wlog.CtxWithAttrs(c.Request.Context(),
 			slog.Any("request", slog.GroupValue(requestAttributes...)),
 			slog.String(sloggin.RequestIDKey, reqId),
 		)
 
-		if enableTrace {
-			span := trace.SpanFromContext(ctx)
-			traceId := span.SpanContext().TraceID().String()
-			spanId := span.SpanContext().SpanID().String()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+	return h1, h2
+}
+
+const ErrorCodeKey = "err_code"
 
-			logger = logger.With(
-				slog.String(sloggin.TraceIDKey, traceId),
-				slog.String(sloggin.SpanIDKey, spanId),
+// GetErrorCode returns the error code which is set by HandleError, 0 indicates success.
+func GetErrorCode(c *gin.Context) int {
+	return c.GetInt(ErrorCodeKey)
+}
+
+func SetErrorCode(c *gin.Context, errCode int) {
+	c.Set(ErrorCodeKey, errCode)
+	sloggin.AddCustomAttributes(c, slog.Int(ErrorCodeKey, errCode))
+}
+
+// HandleError converts the last error of c.Errors into utility.ErrorResponse,
+// it is the same as adapters.HandleErrorByFiber.
+//
+// The panic is recovered and converted into panicErr, e.g. pkg.ErrSystem
+//
+// HandleError must be registered after O11YMetric and O11YLogger,
+// so that they can get the http status and error code.
+func HandleError(wlogger *wlog.Logger, panicErr error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			wlogger.CtxGetLogger(c.Request.Context()).Error("recovered from panic",
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())),
 			)
-		}
+			c.Error(fmt.Errorf("recovered from panic: %v: %w", r, panicErr))
+			writeError(c, wlogger)
+		}()
 
-		c.Request = c.Request.WithContext(Logger.CtxWithLogger(ctx, logger))
 		c.Next()
+		writeError(c, wlogger)
 	}
-	return h1, h2
 }
 
-// GormTX
-//
-// 若 skip == nil, 所有條件都會使用 tx
-// 若 skip != nil, 滿足條件的, 將不會啟動 tx
-func GormTX(db *gorm.DB, skip func(ctx *gin.Context) bool, wlogger *wlog.Logger) gin.HandlerFunc {
-	skipMethods := map[string]bool{
-		http.MethodHead:    true,
-		http.MethodConnect: true,
-		http.MethodOptions: true,
-		http.MethodTrace:   true,
+func writeError(c *gin.Context, wlogger *wlog.Logger) {
+	if len(c.Errors) == 0 {
+		return
 	}
+	err := c.Errors.Last().Err
 
-	return func(c *gin.Context) {
-		canSkip := db == nil ||
-			skipMethods[c.Request.Method] ||
-			(skip != nil && skip(c))
+	logger := wlogger.CtxGetLogger(c.Request.Context())
+	logger.Error(err.Error())
 
-		if canSkip {
-			c.Next()
+	myErr, ok := utility.UnwrapCustomError(err)
+	if !ok {
+		logger.Warn("capture unknown error", slog.Any("err", err))
+	}
+	SetErrorCode(c, myErr.ErrorCode())
+
+	if c.Writer.Written() {
+		return
+	}
+
+	// the internal error chain is logged, client only receives the public message
+	message, details := utility.PublicErrorMessage(err, utility.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+	c.AbortWithStatusJSON(myErr.HttpStatus(), gin.H{"error": &utility.ErrorResponse{
+		Code:    myErr.ErrorCode(),
+		Message: message,
+		Details: details,
+	}})
+}
+
+// Authenticate verifies the bearer token, and then puts utility.Principal into the standard context,
+// the handler gets it by utility.CtxGetPrincipal.
+//
+// unauthorized is used when the token is missing, e.g. pkg.ErrUnauthorized
+func Authenticate(verify utility.TokenVerifier, unauthorized error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := utility.ParseBearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			c.Error(unauthorized)
+			c.Abort()
 			return
 		}
 
-		stdCtx := c.Request.Context()
-		logger := wlogger.CtxGetLogger(stdCtx)
-
-		tx := db.Begin()
-		err := tx.Error
+		ctx := c.Request.Context()
+		principal, err := verify(ctx, token)
 		if err != nil {
-			logger.Error("gorm tx begin failed", "err", err)
 			c.Error(err)
+			c.Abort()
 			return
 		}
 
-		c.Request = c.Request.WithContext(utility.CtxWithGormTX(stdCtx, db, tx))
-
+		ctx = utility.CtxWithPrincipal(ctx, principal)
+		ctx = wlog.CtxWithAttrs(ctx, slog.String("user_id", principal.UserId))
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
-
-		if len(c.Errors) > 0 || c.Writer.Status() >= http.StatusBadRequest {
-			Err := tx.Rollback().Error
-			if Err != nil {
-				logger.Error("gorm tx rollback failed", "err", Err)
-			}
-			return
-		}
-
-		Err := tx.Commit().Error
-		if Err != nil {
-			logger.Error("gorm tx commit failed", "err", Err)
-			c.Error(Err)
-			return
-		}
 	}
 }
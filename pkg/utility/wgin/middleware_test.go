@@ -0,0 +1,212 @@
+package wgin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/KScaesar/go-layout/pkg/utility"
+	"github.com/KScaesar/go-layout/pkg/utility/wlog"
+)
+
+func TestHandleError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := utility.NewErrorRegistry()
+	errSystem := registry.
+		AddErrorCode(5000).
+		AddHttpStatus(http.StatusInternalServerError).
+		NewError("system issue")
+	errInvalidParam := registry.
+		AddErrorCode(4000).
+		AddHttpStatus(http.StatusBadRequest).
+		AddTranslation("zh-TW", "參數錯誤").
+		NewError("invalid parameter")
+
+	router := gin.New()
+	router.Use(
+		O11YMetric("wgin_test"),
+		HandleError(wlog.NewDiscardLogger(), errSystem),
+	)
+	router.GET("/invalid", func(c *gin.Context) {
+		c.Error(utility.ErrorWithDetails(errInvalidParam, utility.ErrorDetail{Field: "email", Message: "invalid format"}))
+	})
+	router.GET("/unknown", func(c *gin.Context) {
+		c.Error(errors.New("sql: connection refused"))
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("nil map")
+	})
+	router.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusConflict, "conflict")
+		c.Error(errInvalidParam)
+	})
+
+	tests := []struct {
+		name     string
+		target   string
+		language string
+		code     int
+		body     string
+		errCode  string
+	}{
+		{
+			name:    "custom error with details",
+			target:  "/invalid",
+			code:    http.StatusBadRequest,
+			body:    `{"error":{"code":4000,"message":"invalid parameter","details":[{"field":"email","message":"invalid format"}]}}`,
+			errCode: "4000",
+		},
+		{
+			name:     "translation",
+			target:   "/invalid",
+			language: "zh-TW",
+			code:     http.StatusBadRequest,
+			body:     `{"error":{"code":4000,"message":"參數錯誤","details":[{"field":"email","message":"invalid format"}]}}`,
+			errCode:  "4000",
+		},
+		{
+			name:    "unknown error",
+			target:  "/unknown",
+			code:    http.StatusInternalServerError,
+			body:    `{"error":{"code":-1,"message":"unknown error"}}`,
+			errCode: "-1",
+		},
+		{
+			name:    "panic",
+			target:  "/panic",
+			code:    http.StatusInternalServerError,
+			body:    `{"error":{"code":5000,"message":"system issue"}}`,
+			errCode: "5000",
+		},
+		{
+			name:    "response is written by handler",
+			target:  "/written",
+			code:    http.StatusConflict,
+			body:    `conflict`,
+			errCode: "4000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.language != "" {
+				req.Header.Set("Accept-Language", tt.language)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.code, recorder.Code)
+			if tt.target == "/written" {
+				assert.Equal(t, tt.body, recorder.Body.String())
+				return
+			}
+			assert.JSONEq(t, tt.body, recorder.Body.String())
+		})
+	}
+
+	// requests_total_errors keeps the labels, the error code is counted by another metric
+	expected := `
+# HELP wgin_test_http_requests_total_error_codes Total number of HTTP errors by error code, see HandleError
+# TYPE wgin_test_http_requests_total_error_codes counter
+wgin_test_http_requests_total_error_codes{err_code="-1",method="GET",route="/unknown"} 1
+wgin_test_http_requests_total_error_codes{err_code="4000",method="GET",route="/invalid"} 2
+wgin_test_http_requests_total_error_codes{err_code="4000",method="GET",route="/written"} 1
+wgin_test_http_requests_total_error_codes{err_code="5000",method="GET",route="/panic"} 1
+# HELP wgin_test_http_requests_total_errors Total number of HTTP errors
+# TYPE wgin_test_http_requests_total_errors counter
+wgin_test_http_requests_total_errors{code="400",method="GET",route="/invalid"} 2
+wgin_test_http_requests_total_errors{code="409",method="GET",route="/written"} 1
+wgin_test_http_requests_total_errors{code="500",method="GET",route="/panic"} 1
+wgin_test_http_requests_total_errors{code="500",method="GET",route="/unknown"} 1
+`
+	err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected),
+		"wgin_test_http_requests_total_error_codes",
+		"wgin_test_http_requests_total_errors",
+	)
+	assert.NoError(t, err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := utility.NewErrorRegistry()
+	errSystem := registry.
+		AddErrorCode(5000).
+		AddHttpStatus(http.StatusInternalServerError).
+		NewError("system issue")
+	errUnauthorized := registry.
+		AddErrorCode(4010).
+		AddHttpStatus(http.StatusUnauthorized).
+		NewError("unauthorized")
+	errRevoked := registry.
+		AddErrorCode(4011).
+		AddHttpStatus(http.StatusUnauthorized).
+		NewError("token has been revoked")
+
+	verify := func(ctx context.Context, token string) (utility.Principal, error) {
+		if token == "revoked" {
+			return utility.Principal{}, errRevoked
+		}
+		return utility.Principal{UserId: token}, nil
+	}
+
+	router := gin.New()
+	router.Use(HandleError(wlog.NewDiscardLogger(), errSystem))
+	router.GET("/me", Authenticate(verify, errUnauthorized), func(c *gin.Context) {
+		principal, _ := utility.CtxGetPrincipal(c.Request.Context())
+		c.String(http.StatusOK, principal.UserId)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		code          int
+		body          string
+	}{
+		{
+			name:          "valid token",
+			authorization: "Bearer u1",
+			code:          http.StatusOK,
+			body:          "u1",
+		},
+		{
+			name: "missing token",
+			code: http.StatusUnauthorized,
+			body: `{"error":{"code":4010,"message":"unauthorized"}}`,
+		},
+		{
+			name:          "not bearer",
+			authorization: "Basic dTE6cGFzc3dvcmQ=",
+			code:          http.StatusUnauthorized,
+			body:          `{"error":{"code":4010,"message":"unauthorized"}}`,
+		},
+		{
+			name:          "verify failed",
+			authorization: "bearer revoked",
+			code:          http.StatusUnauthorized,
+			body:          `{"error":{"code":4011,"message":"token has been revoked"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.code, recorder.Code)
+			assert.Equal(t, tt.body, recorder.Body.String())
+		})
+	}
+}
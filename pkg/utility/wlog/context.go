@@ -0,0 +1,166 @@
+package wlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIdKey = "trace_id"
+	SpanIdKey  = "span_id"
+)
+
+type ctxAttrsKey struct{}
+
+// CtxWithAttrs stores attributes in the context,
+// every record logged with this context will carry these attributes.
+//
+// Example:
+//
+//	ctx = wlog.CtxWithAttrs(ctx, slog.String("req_id", reqId))
+//	slog.InfoContext(ctx, "hello")
+func CtxWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	old := CtxGetAttrs(ctx)
+	merged := make([]slog.Attr, 0, len(old)+len(attrs))
+	merged = append(merged, old...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxAttrsKey{}, merged)
+}
+
+func CtxGetAttrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+func ctxHasAttrs(ctx context.Context) bool {
+	return len(CtxGetAttrs(ctx)) > 0 || trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// NewContextHandler wraps a handler, and adds the following attributes on every record:
+//
+//  1. trace_id and span_id from OpenTelemetry span context
+//  2. attributes stored by CtxWithAttrs
+//
+// If the record or the logger already has an attribute with the same key,
+// the attribute from the context is skipped, so that the output does not have duplicate keys.
+//
+// Note: attributes from the context are added into the current group opened by slog.Logger.WithGroup.
+func NewContextHandler(handler slog.Handler) slog.Handler {
+	if h, ok := handler.(*ContextHandler); ok {
+		return h
+	}
+	return &ContextHandler{handler: handler}
+}
+
+type ContextHandler struct {
+	handler slog.Handler
+
+	// keys are top-level attribute keys added by WithAttrs
+	keys    []string
+	grouped bool
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx == nil || !ctxHasAttrs(ctx) {
+		return h.handler.Handle(ctx, record)
+	}
+
+	exists := func(key string) bool {
+		if slices.Contains(h.keys, key) {
+			return true
+		}
+		found := false
+		record.Attrs(func(a slog.Attr) bool {
+			found = a.Key == key
+			return !found
+		})
+		return found
+	}
+
+	attrs := make([]slog.Attr, 0, 8)
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if spanCtx.IsValid() {
+		if !exists(TraceIdKey) {
+			attrs = append(attrs, slog.String(TraceIdKey, spanCtx.TraceID().String()))
+		}
+		if !exists(SpanIdKey) {
+			attrs = append(attrs, slog.String(SpanIdKey, spanCtx.SpanID().String()))
+		}
+	}
+	for _, attr := range CtxGetAttrs(ctx) {
+		if !exists(attr.Key) {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	if len(attrs) == 0 {
+		return h.handler.Handle(ctx, record)
+	}
+
+	record = record.Clone()
+	record.AddAttrs(attrs...)
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	keys := h.keys
+	if !h.grouped {
+		keys = slices.Clone(h.keys)
+		for _, attr := range attrs {
+			keys = append(keys, attr.Key)
+		}
+	}
+	return &ContextHandler{
+		handler: h.handler.WithAttrs(attrs),
+		keys:    keys,
+		grouped: h.grouped,
+	}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{
+		handler: h.handler.WithGroup(name),
+		keys:    h.keys,
+		grouped: h.grouped || name != "",
+	}
+}
+
+// ctxBoundHandler uses the bound context
+// when the caller logs without context, e.g. logger.Info instead of logger.InfoContext
+type ctxBoundHandler struct {
+	ctx     context.Context
+	handler slog.Handler
+}
+
+func (h *ctxBoundHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(h.choose(ctx), level)
+}
+
+func (h *ctxBoundHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(h.choose(ctx), record)
+}
+
+func (h *ctxBoundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ctxBoundHandler{ctx: h.ctx, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *ctxBoundHandler) WithGroup(name string) slog.Handler {
+	return &ctxBoundHandler{ctx: h.ctx, handler: h.handler.WithGroup(name)}
+}
+
+func (h *ctxBoundHandler) choose(ctx context.Context) context.Context {
+	if ctx == nil || ctx == context.Background() {
+		return h.ctx
+	}
+	return ctx
+}
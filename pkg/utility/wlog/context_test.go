@@ -0,0 +1,60 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	conf := &Config{}
+	conf.SetJsonFormat(true)
+	handler := NewHandler(buf, conf)
+	wlogger := NewLogger(conf.LevelVar, handler)
+
+	traceId, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanId, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+	}))
+	ctx = CtxWithAttrs(ctx, slog.String("req_id", "ctx"), slog.String("user", "caesar"))
+
+	decode := func() map[string]any {
+		line := map[string]any{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		buf.Reset()
+		return line
+	}
+
+	wlogger.Slog().InfoContext(ctx, "by context")
+	line := decode()
+	assert.Equal(t, traceId.String(), line[TraceIdKey])
+	assert.Equal(t, spanId.String(), line[SpanIdKey])
+	assert.Equal(t, "ctx", line["req_id"])
+	assert.Equal(t, "caesar", line["user"])
+
+	wlogger.Slog().With(slog.String("req_id", "logger")).InfoContext(ctx, "logger attr takes precedence")
+	line = decode()
+	assert.Equal(t, "logger", line["req_id"])
+
+	wlogger.Slog().InfoContext(ctx, "record attr takes precedence", slog.String("user", "record"))
+	line = decode()
+	assert.Equal(t, "record", line["user"])
+
+	wlogger.CtxGetLogger(ctx).Info("bound context")
+	line = decode()
+	assert.Equal(t, "ctx", line["req_id"])
+	assert.Equal(t, traceId.String(), line[TraceIdKey])
+
+	wlogger.Slog().Info("without context")
+	line = decode()
+	assert.NotContains(t, line, "req_id")
+}
@@ -0,0 +1,112 @@
+package wlog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is the JSON-lines schema of event/audit log.
+//
+// Example:
+//
+//	{"time":"2025-01-10T23:15:45+08:00","actor":"127.0.0.1","action":"logger.level.update","resource":"logger","outcome":"success","detail":{"level":"DEBUG"}}
+type Event struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	Resource string    `json:"resource"`
+	Outcome  string    `json:"outcome"`
+
+	// Optional Field
+	Reason  string `json:"reason,omitempty"`
+	Detail  any    `json:"detail,omitempty"`
+	TraceId string `json:"trace_id,omitempty"`
+}
+
+// EventLoggerFactory creates an event logger which outputs to file or stdout.
+func EventLoggerFactory(filename string) (logger *EventLogger, w io.WriteCloser, err error) {
+	if filename != "" {
+		w, err = NewRotateWriter(filename, -1)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		w = os.Stdout
+	}
+	return NewEventLogger(w), w, nil
+}
+
+func NewEventLogger(w io.Writer) *EventLogger {
+	return &EventLogger{
+		w:   w,
+		now: time.Now,
+	}
+}
+
+// EventLogger writes domain events and security actions,
+// it is separated from the operation log, so that the audit trail is not affected by the logger level.
+type EventLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func (l *EventLogger) Emit(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = l.now()
+	}
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSuccess
+	}
+	if event.TraceId == "" {
+		spanCtx := trace.SpanContextFromContext(ctx)
+		if spanCtx.IsValid() {
+			event.TraceId = spanCtx.TraceID().String()
+		}
+	}
+
+	bData, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	bData = append(bData, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(bData)
+	return err
+}
+
+// EmitResult is a shortcut to Emit, the outcome and reason are determined by err.
+func (l *EventLogger) EmitResult(ctx context.Context, event Event, err error) error {
+	if err != nil {
+		event.Outcome = OutcomeFailure
+		event.Reason = err.Error()
+	}
+	return l.Emit(ctx, event)
+}
+
+// PointToNew
+// 通過改變指標的指向, 讓所有引用此指標的其他元件獲得最新的狀態
+func (l *EventLogger) PointToNew(new *EventLogger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	new.mu.Lock()
+	defer new.mu.Unlock()
+
+	l.w = new.w
+	l.now = new.now
+}
@@ -0,0 +1,71 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEventLogger_Emit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewEventLogger(buf)
+	now := time.Date(2025, 1, 10, 23, 15, 45, 0, time.UTC)
+	logger.now = func() time.Time { return now }
+
+	traceId, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanId, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+	}))
+
+	err := logger.Emit(ctx, Event{
+		Actor:    "127.0.0.1",
+		Action:   "logger.level.update",
+		Resource: "logger",
+		Detail:   map[string]any{"level": "DEBUG"},
+	})
+	require.NoError(t, err)
+
+	// the record is a single json line, and the schema is stable for the downstream parsers
+	assert.Equal(t,
+		`{"time":"2025-01-10T23:15:45Z","actor":"127.0.0.1","action":"logger.level.update","resource":"logger","outcome":"success","detail":{"level":"DEBUG"},"trace_id":"0102030405060708090a0b0c0d0e0f10"}`+"\n",
+		buf.String(),
+	)
+}
+
+func TestEventLogger_EmitResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantOutcome string
+		wantReason  string
+	}{
+		{name: "success", err: nil, wantOutcome: OutcomeSuccess},
+		{name: "failure", err: errors.New("permission denied"), wantOutcome: OutcomeFailure, wantReason: "permission denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := NewEventLogger(buf)
+
+			err := logger.EmitResult(context.Background(), Event{Actor: "caesar", Action: "user.login", Resource: "user"}, tt.err)
+			require.NoError(t, err)
+
+			var event Event
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+			assert.Equal(t, tt.wantOutcome, event.Outcome)
+			assert.Equal(t, tt.wantReason, event.Reason)
+			assert.False(t, event.Time.IsZero())
+			assert.Empty(t, event.TraceId)
+		})
+	}
+}
@@ -112,17 +112,20 @@ func NewHandler(w io.Writer, conf *Config) slog.Handler {
 	return handler
 }
 
+// NewLogger fans out records to all handlers,
+// and the records automatically carry the attributes of context, see NewContextHandler.
 func NewLogger(lvl *slog.LevelVar, handlers ...slog.Handler) *Logger {
 	return &Logger{
 		lvl:    lvl,
-		logger: slog.New(slogmulti.Fanout(handlers...)),
+		logger: slog.New(NewContextHandler(slogmulti.Fanout(handlers...))),
 	}
 }
 
 type Logger struct {
-	mu     sync.RWMutex
-	lvl    *slog.LevelVar
-	logger *slog.Logger
+	mu       sync.RWMutex
+	lvl      *slog.LevelVar
+	logger   *slog.Logger
+	reloader *Reloader
 }
 
 func (l *Logger) Slog() *slog.Logger {
@@ -141,22 +144,61 @@ func (l *Logger) CtxWithLogger(ctx context.Context, v *slog.Logger) context.Cont
 	return context.WithValue(ctx, l.logger, v)
 }
 
+// CtxGetLogger returns the logger stored by CtxWithLogger.
+// Otherwise, it returns a logger bound to ctx,
+// so that logger.Info without context still carries the attributes of CtxWithAttrs.
 func (l *Logger) CtxGetLogger(ctx context.Context) (logger *slog.Logger) {
 	v, ok := ctx.Value(l.logger).(*slog.Logger)
-	if !ok {
+	if ok {
+		return v
+	}
+	if !ctxHasAttrs(ctx) {
 		return l.logger
 	}
-	return v
+	return slog.New(&ctxBoundHandler{ctx: ctx, handler: l.logger.Handler()})
 }
 
+// Level implements slog.Leveler, it follows the LevelVar replaced by PointToNew,
+// so Logger can be passed to the handler which is created before PointToNew, e.g. OTLP handler.
 func (l *Logger) Level() slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.lvl.Level()
 }
 
 func (l *Logger) SetLevel(lvl slog.Level) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	l.lvl.Set(lvl)
 }
 
+// Reload rebuilds the handlers by Config and output file without restart,
+// only the logger created by LoggerFactory supports it.
+//
+// The nil fields of conf keep the current value, see Reloader.Reload
+func (l *Logger) Reload(filename string, conf *Config) error {
+	l.mu.RLock()
+	reloader := l.reloader
+	l.mu.RUnlock()
+
+	if reloader == nil {
+		return ErrLoggerNotReloadable
+	}
+	return reloader.Reload(filename, conf)
+}
+
+// Filename returns the current output file, empty means stderr
+func (l *Logger) Filename() string {
+	l.mu.RLock()
+	reloader := l.reloader
+	l.mu.RUnlock()
+
+	if reloader == nil {
+		return ""
+	}
+	return reloader.Filename()
+}
+
 func (l *Logger) SetStdDefaultLevel() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -186,5 +228,6 @@ func (l *Logger) PointToNew(new *Logger) {
 	defer l.mu.Unlock()
 
 	l.lvl = new.lvl // 維持 slog.Handler 對 LevelVar 的引用
+	l.reloader = new.reloader
 	*l.logger = *(new.logger)
 }
@@ -0,0 +1,36 @@
+package wlog
+
+import (
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLogger_PointToNew the handler leveled by Logger follows the new LevelVar,
+// it is run with -race to ensure Level does not race with PointToNew.
+func TestLogger_PointToNew(t *testing.T) {
+	logger := NewDiscardLogger()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.Level()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.PointToNew(NewDiscardLogger())
+		}
+	}()
+	wg.Wait()
+
+	debug := NewDiscardLogger()
+	debug.SetLevel(slog.LevelDebug)
+	logger.PointToNew(debug)
+	assert.Equal(t, slog.LevelDebug, logger.Level())
+}
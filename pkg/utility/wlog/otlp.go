@@ -0,0 +1,155 @@
+package wlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+type OTLPConfig struct {
+	// Endpoint is host:port of OTLP/HTTP receiver, e.g. localhost:4318
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+
+	// batch
+	ExportInterval time.Duration
+	MaxQueueSize   int
+
+	// retry
+	RetryInitialInterval time.Duration
+	RetryMaxInterval     time.Duration
+	RetryMaxElapsedTime  time.Duration
+}
+
+func (conf *OTLPConfig) defaultValue() {
+	if conf.ExportInterval <= 0 {
+		conf.ExportInterval = time.Second
+	}
+	if conf.MaxQueueSize <= 0 {
+		conf.MaxQueueSize = 2048
+	}
+	if conf.RetryInitialInterval <= 0 {
+		conf.RetryInitialInterval = 500 * time.Millisecond
+	}
+	if conf.RetryMaxInterval <= 0 {
+		conf.RetryMaxInterval = 5 * time.Second
+	}
+	if conf.RetryMaxElapsedTime <= 0 {
+		conf.RetryMaxElapsedTime = 30 * time.Second
+	}
+}
+
+// NewOTLPHandler creates a slog.Handler which exports records to OTLP/HTTP receiver.
+// It is designed to be fanned out with other handlers by NewLogger.
+//
+// The trace_id and span_id are attached from the context of slog.Record,
+// so it's necessary to use slog.InfoContext(ctx, ...) to correlate log with trace.
+//
+// Records are batched in memory and exported at fixed intervals,
+// failed exports are retried with exponential backoff.
+// OTLPHandler.Shutdown must be called to flush the remaining records before process exit.
+func NewOTLPHandler(conf *OTLPConfig, lvl slog.Leveler) (*OTLPHandler, error) {
+	conf.defaultValue()
+
+	opts := []otlploghttp.Option{
+		otlploghttp.WithEndpoint(conf.Endpoint),
+		otlploghttp.WithRetry(otlploghttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: conf.RetryInitialInterval,
+			MaxInterval:     conf.RetryMaxInterval,
+			MaxElapsedTime:  conf.RetryMaxElapsedTime,
+		}),
+	}
+	if conf.Insecure {
+		opts = append(opts, otlploghttp.WithInsecure())
+	}
+
+	exporter, err := otlploghttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP log exporter: %w", err)
+	}
+
+	processor := sdklog.NewBatchProcessor(exporter,
+		sdklog.WithExportInterval(conf.ExportInterval),
+		sdklog.WithMaxQueueSize(conf.MaxQueueSize),
+	)
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(processor),
+		sdklog.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(conf.ServiceName),
+		)),
+	)
+
+	if lvl == nil {
+		lvl = slog.LevelInfo
+	}
+
+	return &OTLPHandler{
+		handler:  otelslog.NewHandler(conf.ServiceName, otelslog.WithLoggerProvider(provider)),
+		provider: provider,
+		lvl:      lvl,
+	}, nil
+}
+
+type OTLPHandler struct {
+	handler  slog.Handler
+	provider *sdklog.LoggerProvider
+	lvl      slog.Leveler
+}
+
+func (h *OTLPHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.lvl.Level()
+}
+
+// Handle drops trace_id and span_id attributes added by ContextHandler,
+// because OTLP log record carries them as native fields.
+func (h *OTLPHandler) Handle(ctx context.Context, record slog.Record) error {
+	hasTraceAttr := false
+	record.Attrs(func(a slog.Attr) bool {
+		hasTraceAttr = a.Key == TraceIdKey || a.Key == SpanIdKey
+		return !hasTraceAttr
+	})
+	if !hasTraceAttr {
+		return h.handler.Handle(ctx, record)
+	}
+
+	r := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		if a.Key != TraceIdKey && a.Key != SpanIdKey {
+			r.AddAttrs(a)
+		}
+		return true
+	})
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *OTLPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &OTLPHandler{
+		handler:  h.handler.WithAttrs(attrs),
+		provider: h.provider,
+		lvl:      h.lvl,
+	}
+}
+
+func (h *OTLPHandler) WithGroup(name string) slog.Handler {
+	return &OTLPHandler{
+		handler:  h.handler.WithGroup(name),
+		provider: h.provider,
+		lvl:      h.lvl,
+	}
+}
+
+// Shutdown flushes the buffered records and stops the exporter.
+func (h *OTLPHandler) Shutdown(ctx context.Context) error {
+	return h.provider.Shutdown(ctx)
+}
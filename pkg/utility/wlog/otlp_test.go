@@ -0,0 +1,112 @@
+package wlog
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type stubOTLPReceiver struct {
+	mu      sync.Mutex
+	records []*logspb.LogRecord
+
+	// the number of requests should be rejected before accepting
+	reject atomic.Int32
+}
+
+func (recv *stubOTLPReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/logs" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if recv.reject.Add(-1) >= 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	bData, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(bData, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	recv.mu.Lock()
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			recv.records = append(recv.records, sl.LogRecords...)
+		}
+	}
+	recv.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	bResp, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+	w.Write(bResp)
+}
+
+func TestOTLPHandler(t *testing.T) {
+	recv := &stubOTLPReceiver{}
+	recv.reject.Store(1)
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	lvl := &slog.LevelVar{}
+	handler, err := NewOTLPHandler(&OTLPConfig{
+		Endpoint:             strings.TrimPrefix(server.URL, "http://"),
+		Insecure:             true,
+		ServiceName:          "test",
+		ExportInterval:       50 * time.Millisecond,
+		RetryInitialInterval: 10 * time.Millisecond,
+		RetryMaxInterval:     10 * time.Millisecond,
+	}, lvl)
+	require.NoError(t, err)
+
+	logger := NewLogger(lvl, NewHandler(io.Discard, &Config{}), handler)
+
+	traceId, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanId, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	logger.Slog().DebugContext(ctx, "debug is filtered")
+	logger.Slog().With(slog.String("user", "caesar")).InfoContext(ctx, "hello")
+
+	require.NoError(t, handler.Shutdown(context.Background()))
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	require.Len(t, recv.records, 1)
+
+	record := recv.records[0]
+	assert.Equal(t, "hello", record.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, record.SeverityNumber)
+	assert.Equal(t, traceId.String(), hex.EncodeToString(record.TraceId))
+	assert.Equal(t, spanId.String(), hex.EncodeToString(record.SpanId))
+	require.Len(t, record.Attributes, 1)
+	assert.Equal(t, "user", record.Attributes[0].Key)
+	assert.Equal(t, "caesar", record.Attributes[0].Value.GetStringValue())
+}
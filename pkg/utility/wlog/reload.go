@@ -0,0 +1,227 @@
+package wlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// reloadRoot holds the current handler, the swap is protected by RWMutex,
+// records being handled by the old handler are finished before the old writer is closed.
+type reloadRoot struct {
+	mu         sync.RWMutex
+	handler    slog.Handler
+	generation atomic.Uint64
+}
+
+type reloadCache struct {
+	generation uint64
+	handler    slog.Handler
+}
+
+// reloadHandler
+// 由於 slog.Logger.With 會產生新的 handler, 切換 handler 之後, 舊的衍生 logger 無法獲得最新的狀態.
+// 因此記錄 WithAttrs, WithGroup 的操作, 當 root 切換之後, 再重新套用到新的 handler
+type reloadHandler struct {
+	root  *reloadRoot
+	ops   []func(slog.Handler) slog.Handler
+	cache atomic.Pointer[reloadCache]
+}
+
+func (h *reloadHandler) current() slog.Handler {
+	generation := h.root.generation.Load()
+	cache := h.cache.Load()
+	if cache != nil && cache.generation == generation {
+		return cache.handler
+	}
+
+	handler := h.root.handler
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	h.cache.Store(&reloadCache{generation: generation, handler: handler})
+	return handler
+}
+
+func (h *reloadHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	h.root.mu.RLock()
+	defer h.root.mu.RUnlock()
+	return h.root.handler.Enabled(ctx, level)
+}
+
+func (h *reloadHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.root.mu.RLock()
+	defer h.root.mu.RUnlock()
+	return h.current().Handle(ctx, record)
+}
+
+func (h *reloadHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+func (h *reloadHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+func (h *reloadHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, 0, len(h.ops)+1)
+	ops = append(ops, h.ops...)
+	ops = append(ops, op)
+	return &reloadHandler{root: h.root, ops: ops}
+}
+
+//
+
+func newReloader(filename string, w io.WriteCloser, conf *Config) *Reloader {
+	return &Reloader{
+		root:     &reloadRoot{handler: NewHandler(w, conf)},
+		filename: filename,
+		conf:     conf,
+		w:        w,
+	}
+}
+
+// Reloader rebuilds the handler of Logger at runtime when Config or output file changes.
+//
+// It is also an io.WriteCloser which always points to the current output,
+// so that the caller of LoggerFactory can close the latest file.
+type Reloader struct {
+	mu       sync.Mutex
+	root     *reloadRoot
+	filename string
+	conf     *Config
+	w        io.WriteCloser
+}
+
+func (r *Reloader) handler() slog.Handler {
+	return &reloadHandler{root: r.root}
+}
+
+func (r *Reloader) Filename() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filename
+}
+
+// Reload swaps the handler atomically.
+//
+// The nil fields of conf inherit the current Config,
+// and LevelVar is kept, so that the level changed by SetLevel is still effective
+// unless conf.Level is specified.
+func (r *Reloader) Reload(filename string, conf *Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newConf := r.mergeConfig(conf)
+
+	w := r.w
+	if filename != r.filename {
+		var err error
+		w, err = openOutput(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	handler := NewHandler(w, newConf)
+
+	r.root.mu.Lock()
+	r.root.handler = handler
+	r.root.generation.Add(1)
+	r.root.mu.Unlock()
+
+	if w != r.w {
+		closeOutput(r.w)
+	}
+
+	r.filename = filename
+	r.conf = newConf
+	r.w = w
+	return nil
+}
+
+func (r *Reloader) mergeConfig(conf *Config) *Config {
+	old := r.conf
+	newConf := &Config{
+		AddSource:  conf.AddSource,
+		JsonFormat: conf.JsonFormat,
+		NoColor:    conf.NoColor,
+		Formats:    conf.Formats,
+		LevelVar:   old.LevelVar,
+	}
+
+	if newConf.AddSource == nil {
+		newConf.AddSource = old.AddSource
+	}
+	if newConf.JsonFormat == nil {
+		newConf.JsonFormat = old.JsonFormat
+	}
+	if newConf.NoColor == nil {
+		newConf.NoColor = old.NoColor
+	}
+	if newConf.Formats == nil {
+		newConf.Formats = old.Formats
+	}
+
+	if conf.Level != nil {
+		level := *conf.Level
+		newConf.Level = &level
+		newConf.LevelVar.Set(slog.Level(level))
+	} else {
+		newConf.Level = old.Level
+	}
+	return newConf
+}
+
+func (r *Reloader) Write(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.w.Write(p)
+}
+
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return closeOutput(r.w)
+}
+
+func openOutput(filename string) (io.WriteCloser, error) {
+	if filename == "" {
+		return os.Stderr, nil
+	}
+	return NewRotateWriter(filename, -1)
+}
+
+func closeOutput(w io.WriteCloser) error {
+	if w == os.Stderr || w == os.Stdout {
+		return nil
+	}
+	return w.Close()
+}
+
+var ErrLoggerNotReloadable = errors.New("logger is not created by LoggerFactory")
+
+// ReloadRequest is the body of admin endpoint, the omitted fields keep the current value.
+//
+// The output file can't be changed by request,
+// otherwise the caller is able to create and write the file at any path.
+//
+// Example:
+//
+//	{"JsonFormat":true,"AddSource":false,"NoColor":true,"Level":-4}
+type ReloadRequest struct {
+	Config
+}
+
+// ReloadByRequest only reopens the configured file, see Logger.Filename
+func (l *Logger) ReloadByRequest(req *ReloadRequest) error {
+	return l.Reload(l.Filename(), &req.Config)
+}
@@ -0,0 +1,64 @@
+package wlog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Reload(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "1.log")
+	file2 := filepath.Join(dir, "2.log")
+
+	conf := &Config{}
+	conf.SetJsonFormat(true)
+	wlogger, w, err := LoggerFactory(file1, conf)
+	require.NoError(t, err)
+
+	derived := wlogger.Slog().With(slog.String("svc", "test"))
+	derived.Info("before reload")
+
+	debug := int(slog.LevelDebug)
+	conf2 := &Config{Level: &debug}
+	conf2.SetJsonFormat(false)
+	require.NoError(t, wlogger.Reload(file2, conf2))
+	assert.Equal(t, slog.LevelDebug, wlogger.Level())
+	assert.Equal(t, file2, wlogger.Filename())
+
+	derived.Debug("after reload")
+	require.NoError(t, w.Close())
+
+	bData1, err := os.ReadFile(file1)
+	require.NoError(t, err)
+	assert.Contains(t, string(bData1), `"msg":"before reload","svc":"test"`)
+	assert.NotContains(t, string(bData1), "after reload")
+
+	bData2, err := os.ReadFile(file2)
+	require.NoError(t, err)
+	assert.Contains(t, string(bData2), "DBG after reload svc=test")
+}
+
+func TestLogger_ReloadByRequest(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.log")
+	other := filepath.Join(dir, "other.log")
+
+	wlogger, w, err := LoggerFactory(file, &Config{})
+	require.NoError(t, err)
+	defer w.Close()
+
+	var req ReloadRequest
+	body := `{"Filepath":"` + other + `","Level":-4}`
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	require.NoError(t, wlogger.ReloadByRequest(&req))
+
+	assert.Equal(t, slog.LevelDebug, wlogger.Level())
+	assert.Equal(t, file, wlogger.Filename())
+	assert.NoFileExists(t, other)
+}
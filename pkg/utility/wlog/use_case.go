@@ -2,23 +2,32 @@ package wlog
 
 import (
 	"io"
+	"log/slog"
 	"os"
 )
 
-func LoggerFactory(filename string, conf *Config) (logger *Logger, w io.WriteCloser, err error) {
-	if filename != "" {
-		w, err = NewRotateWriter(filename, -1)
-		if err != nil {
-			return nil, nil, err
+// LoggerFactory creates a logger which outputs to file or stderr,
+// the extra handlers (e.g. OTLPHandler) are fanned out together, nil handler is ignored.
+//
+// The logger supports Logger.Reload, and the returned w always points to the current output.
+func LoggerFactory(filename string, conf *Config, extra ...slog.Handler) (logger *Logger, w io.WriteCloser, err error) {
+	output, err := openOutput(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conf.defaultValue()
+	reloader := newReloader(filename, output, conf)
+
+	handlers := []slog.Handler{reloader.handler()}
+	for _, handler := range extra {
+		if handler != nil {
+			handlers = append(handlers, handler)
 		}
-		handler := NewHandler(w, conf)
-		logger = NewLogger(conf.LevelVar, handler)
-	} else {
-		w = os.Stderr
-		handler := NewHandler(w, conf)
-		logger = NewLogger(conf.LevelVar, handler)
 	}
-	return logger, w, nil
+	logger = NewLogger(conf.LevelVar, handlers...)
+	logger.reloader = reloader
+	return logger, reloader, nil
 }
 
 func NewStderrLogger(conf *Config) *Logger {
@@ -0,0 +1,353 @@
+package wlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// ParseJsonRecord converts a line of JsonFormat output into slog.Record,
+// so that the line can be rendered again by any slog.Handler.
+//
+// The order of attributes is preserved, and json object is converted to slog.Group.
+func ParseJsonRecord(line []byte) (record slog.Record, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	attrs, err := decodeJsonObject(decoder)
+	if err != nil {
+		return record, err
+	}
+
+	var (
+		t     time.Time
+		level slog.Level
+		msg   string
+	)
+	others := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		switch attr.Key {
+		case slog.TimeKey:
+			t, err = time.Parse(time.RFC3339Nano, attr.Value.String())
+			if err != nil {
+				return record, fmt.Errorf("parse time: %w", err)
+			}
+		case slog.LevelKey:
+			err = level.UnmarshalText([]byte(attr.Value.String()))
+			if err != nil {
+				return record, fmt.Errorf("parse level: %w", err)
+			}
+		case slog.MessageKey:
+			msg = attr.Value.String()
+		default:
+			others = append(others, attr)
+		}
+	}
+
+	record = slog.NewRecord(t, level, msg, 0)
+	record.AddAttrs(others...)
+	return record, nil
+}
+
+func decodeJsonObject(decoder *json.Decoder) ([]slog.Attr, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, errors.New("json object is expected")
+	}
+
+	attrs := make([]slog.Attr, 0, 8)
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+
+		value, err := decodeJsonValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: value})
+	}
+
+	// consume '}'
+	_, err = decoder.Token()
+	return attrs, err
+}
+
+func decodeJsonValue(decoder *json.Decoder) (slog.Value, error) {
+	if !decoder.More() {
+		return slog.Value{}, errors.New("json value is expected")
+	}
+
+	var raw json.RawMessage
+	err := decoder.Decode(&raw)
+	if err != nil {
+		return slog.Value{}, err
+	}
+
+	switch raw[0] {
+	case '{':
+		sub := json.NewDecoder(bytes.NewReader(raw))
+		sub.UseNumber()
+		attrs, err := decodeJsonObject(sub)
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return slog.GroupValue(attrs...), nil
+
+	case '[':
+		return slog.StringValue(string(raw)), nil
+
+	case '"':
+		var str string
+		err = json.Unmarshal(raw, &str)
+		return slog.StringValue(str), err
+
+	case 't', 'f':
+		return slog.BoolValue(raw[0] == 't'), nil
+
+	case 'n':
+		return slog.StringValue("null"), nil
+
+	default:
+		number := json.Number(raw)
+		if i, err := number.Int64(); err == nil {
+			return slog.Int64Value(i), nil
+		}
+		f, err := number.Float64()
+		return slog.Float64Value(f), err
+	}
+}
+
+//
+
+// RecordFilter decides which records are shown, zero value matches all records.
+type RecordFilter struct {
+	Level   *slog.Level
+	Since   time.Time
+	Until   time.Time
+	ReqId   string
+	TraceId string
+
+	// Attrs are matched by equality of string form,
+	// the key of group attribute is joined by dot, e.g. request.route
+	Attrs map[string]string
+}
+
+func (f *RecordFilter) Match(record slog.Record) bool {
+	if f.Level != nil && record.Level < *f.Level {
+		return false
+	}
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && record.Time.After(f.Until) {
+		return false
+	}
+
+	expected := make(map[string]string, len(f.Attrs)+2)
+	for key, val := range f.Attrs {
+		expected[key] = val
+	}
+	if f.ReqId != "" {
+		expected["req_id"] = f.ReqId
+	}
+	if f.TraceId != "" {
+		expected[TraceIdKey] = f.TraceId
+	}
+	if len(expected) == 0 {
+		return true
+	}
+
+	matched := 0
+	var walk func(prefix string, attr slog.Attr)
+	walk = func(prefix string, attr slog.Attr) {
+		key := prefix + attr.Key
+		if attr.Value.Kind() == slog.KindGroup {
+			for _, sub := range attr.Value.Group() {
+				walk(key+".", sub)
+			}
+			return
+		}
+		val, ok := expected[key]
+		if ok && val == attr.Value.String() {
+			matched++
+		}
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		walk("", attr)
+		return true
+	})
+	return matched == len(expected)
+}
+
+//
+
+// NewViewer renders JsonFormat log lines with the same handler as NewHandler in text format.
+func NewViewer(w io.Writer, noColor bool, filter *RecordFilter) *Viewer {
+	conf := &Config{}
+	conf.SetJsonFormat(false).
+		SetNoColor(noColor).
+		SetLevelVar(int(slog.LevelDebug - 4))
+
+	if filter == nil {
+		filter = &RecordFilter{}
+	}
+
+	return &Viewer{
+		handler: NewHandler(w, conf),
+		filter:  filter,
+		out:     w,
+	}
+}
+
+type Viewer struct {
+	handler slog.Handler
+	filter  *RecordFilter
+	out     io.Writer
+}
+
+// Line renders a line, the line which is not json is output as it is.
+func (v *Viewer) Line(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+
+	record, err := ParseJsonRecord(line)
+	if err != nil {
+		if v.filter.empty() {
+			_, err = fmt.Fprintf(v.out, "%s\n", line)
+			return err
+		}
+		return nil
+	}
+
+	if !v.filter.Match(record) {
+		return nil
+	}
+	return v.handler.Handle(context.Background(), record)
+}
+
+func (f *RecordFilter) empty() bool {
+	return f.Level == nil && f.Since.IsZero() && f.Until.IsZero() &&
+		f.ReqId == "" && f.TraceId == "" && len(f.Attrs) == 0
+}
+
+// Read renders all lines until EOF.
+func (v *Viewer) Read(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 64<<10)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if Err := v.Line(line); Err != nil {
+				return Err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Tail renders all lines of file, and waits for new lines like `tail -f`,
+// the file is reopened when it is rotated by RotateWriter or logrotate.
+func (v *Viewer) Tail(ctx context.Context, filename string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+	}()
+
+	reader := bufio.NewReaderSize(file, 64<<10)
+	var partial []byte
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		partial = append(partial, line...)
+		if err == nil {
+			if Err := v.Line(partial); Err != nil {
+				return Err
+			}
+			partial = partial[:0]
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		rotated, err := isRotated(file, filename)
+		if err != nil || !rotated {
+			continue
+		}
+
+		newFile, err := os.Open(filename)
+		if err != nil {
+			continue
+		}
+		file.Close()
+		file = newFile
+		reader.Reset(file)
+		partial = partial[:0]
+	}
+}
+
+func isRotated(file *os.File, filename string) (bool, error) {
+	current, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	latest, err := os.Stat(filename)
+	if err != nil {
+		return false, err
+	}
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, latest) || latest.Size() < offset, nil
+}
+
+// ParseAttrFilters converts ["key=value", ...] into map for RecordFilter.Attrs
+func ParseAttrFilters(pairs []string) (map[string]string, error) {
+	attrs := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid attr filter %q, expect key=value", pair)
+		}
+		attrs[key] = val
+	}
+	return attrs, nil
+}
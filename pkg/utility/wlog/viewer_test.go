@@ -0,0 +1,198 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJsonRecord(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantErr   bool
+		wantLevel slog.Level
+		wantMsg   string
+		wantAttrs string // key=value joined by space, the group is flattened by dot
+	}{
+		{
+			name:      "flat",
+			line:      `{"time":"2025-01-10T23:15:45.123+08:00","level":"INFO","msg":"hello","req_id":"abc","n":3,"f":1.5,"ok":true,"nil":null}`,
+			wantLevel: slog.LevelInfo,
+			wantMsg:   "hello",
+			wantAttrs: "req_id=abc n=3 f=1.5 ok=true nil=null",
+		},
+		{
+			name:      "group and array",
+			line:      `{"time":"2025-01-10T23:15:45Z","level":"WARN+2","msg":"group","request":{"method":"GET","route":"/users"},"ids":[1,2]}`,
+			wantLevel: slog.LevelWarn + 2,
+			wantMsg:   "group",
+			wantAttrs: "request.method=GET request.route=/users ids=[1,2]",
+		},
+		{name: "not json", line: `plain text`, wantErr: true},
+		{name: "json array", line: `[1,2]`, wantErr: true},
+		{name: "invalid time", line: `{"time":"yesterday","level":"INFO","msg":"x"}`, wantErr: true},
+		{name: "invalid level", line: `{"time":"2025-01-10T23:15:45Z","level":"LOUD","msg":"x"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := ParseJsonRecord([]byte(tt.line))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLevel, record.Level)
+			assert.Equal(t, tt.wantMsg, record.Message)
+			assert.Equal(t, tt.wantAttrs, flattenAttrs(record))
+		})
+	}
+}
+
+func flattenAttrs(record slog.Record) string {
+	var pairs []string
+	var walk func(prefix string, attr slog.Attr)
+	walk = func(prefix string, attr slog.Attr) {
+		if attr.Value.Kind() == slog.KindGroup {
+			for _, sub := range attr.Value.Group() {
+				walk(prefix+attr.Key+".", sub)
+			}
+			return
+		}
+		pairs = append(pairs, prefix+attr.Key+"="+attr.Value.String())
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		walk("", attr)
+		return true
+	})
+	return strings.Join(pairs, " ")
+}
+
+func TestRecordFilter_Match(t *testing.T) {
+	line := `{"time":"2025-01-10T12:00:00Z","level":"WARN","msg":"x","req_id":"abc","trace_id":"t1","request":{"route":"/users"}}`
+	record, err := ParseJsonRecord([]byte(line))
+	require.NoError(t, err)
+
+	info, errLevel := slog.LevelInfo, slog.LevelError
+	noon := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter RecordFilter
+		want   bool
+	}{
+		{name: "zero value", filter: RecordFilter{}, want: true},
+		{name: "level lower", filter: RecordFilter{Level: &info}, want: true},
+		{name: "level higher", filter: RecordFilter{Level: &errLevel}, want: false},
+		{name: "since before", filter: RecordFilter{Since: noon.Add(-time.Minute)}, want: true},
+		{name: "since after", filter: RecordFilter{Since: noon.Add(time.Minute)}, want: false},
+		{name: "until before", filter: RecordFilter{Until: noon.Add(-time.Minute)}, want: false},
+		{name: "req_id", filter: RecordFilter{ReqId: "abc"}, want: true},
+		{name: "req_id mismatch", filter: RecordFilter{ReqId: "xyz"}, want: false},
+		{name: "trace_id", filter: RecordFilter{TraceId: "t1"}, want: true},
+		{name: "group attr", filter: RecordFilter{Attrs: map[string]string{"request.route": "/users"}}, want: true},
+		{name: "missing attr", filter: RecordFilter{Attrs: map[string]string{"request.method": "GET"}}, want: false},
+		{name: "all", filter: RecordFilter{ReqId: "abc", TraceId: "t1", Attrs: map[string]string{"request.route": "/users"}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(record))
+		})
+	}
+}
+
+func TestParseAttrFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", pairs: nil, want: map[string]string{}},
+		{name: "pairs", pairs: []string{"a=1", "request.route=/x=y"}, want: map[string]string{"a": "1", "request.route": "/x=y"}},
+		{name: "empty value", pairs: []string{"a="}, want: map[string]string{"a": ""}},
+		{name: "no separator", pairs: []string{"a"}, wantErr: true},
+		{name: "empty key", pairs: []string{"=1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAttrFilters(tt.pairs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// syncBuffer is written by Tail and read by test concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestViewer_Tail(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	writeLine := func(msg string) {
+		file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"time":"2025-01-10T12:00:00Z","level":"INFO","msg":"` + msg + `"}` + "\n")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+	writeLine("before-rotate")
+
+	out := &syncBuffer{}
+	viewer := NewViewer(out, true, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- viewer.Tail(ctx, filename, 10*time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "before-rotate")
+	}, time.Second, 10*time.Millisecond)
+
+	writeLine("append")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "append")
+	}, time.Second, 10*time.Millisecond)
+
+	// rotate by rename, the same as logrotate, and then the writer creates a new file
+	require.NoError(t, os.Rename(filename, filename+".1"))
+	writeLine("after-rotate")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "after-rotate")
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, strings.Count(out.String(), "before-rotate"))
+}